@@ -0,0 +1,388 @@
+package vfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strings"
+	"time"
+)
+
+// AdminOptions configures an admin handler.
+type AdminOptions struct {
+	// Authorize is called before a request is handled. If it returns a non-nil error,
+	// the request is rejected with 403 Forbidden.
+	// If left nil, all requests are allowed.
+	Authorize func(req *http.Request) error
+	
+	// MaxSize is the maximum size of a request body.
+	// If left zero, it defaults to 32 MiB.
+	MaxSize int64
+	
+	// MaxExtractedSize is the maximum total uncompressed size of the files in an uploaded archive.
+	// If left zero, it defaults to 4 times MaxSize.
+	MaxExtractedSize int64
+	
+	// Audit is called after every request that attempts to change the filesystem.
+	Audit func(AuditEvent)
+}
+
+// AuditEvent describes a request that attempted to change the filesystem.
+type AuditEvent struct {
+	Time       time.Time
+	Method     string
+	Path       string
+	RemoteAddr string
+	Status     int   // HTTP status code of the response.
+	Files      int   // Number of files written.
+	Err        error // Reason the request failed, if it did.
+}
+
+// FileMetadata is the JSON representation of a file or directory served by the admin and remote handlers.
+type FileMetadata struct {
+	Name    string         `json:"name"`
+	Size    int64          `json:"size"`
+	Mode    os.FileMode    `json:"mode"`
+	ModTime time.Time      `json:"modTime"`
+	IsDir   bool           `json:"isDir"`
+	Meta    *FileMeta      `json:"meta,omitempty"`
+	Entries []FileMetadata `json:"entries,omitempty"`
+}
+
+// NewAdminHandler returns a handler for managing fs over HTTP, with the request
+// URL path used as the path within fs:
+//
+//	GET     returns FileMetadata as JSON, including directory entries
+//	PUT     writes the request body to a file, creating missing parent directories
+//	DELETE  removes a file or directory
+//	POST    replaces a directory with the contents of a zip, tar or tar.gz request body
+//
+// Replacing a directory happens atomically, readers never see a partially extracted archive.
+func NewAdminHandler(fs *FS, opt AdminOptions) http.Handler {
+	if opt.MaxSize == 0 {
+		opt.MaxSize = 32 << 20
+	}
+	if opt.MaxExtractedSize == 0 {
+		opt.MaxExtractedSize = 4 * opt.MaxSize
+	}
+	return &adminHandler{fs: fs, opt: opt}
+}
+
+type adminHandler struct {
+	fs  *FS
+	opt AdminOptions
+}
+
+// adminError is an error with an HTTP status code.
+type adminError struct {
+	status int
+	err    error
+}
+
+func (e *adminError) Error() string { return e.err.Error() }
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := pathpkg.Clean("/" + req.URL.Path)
+	
+	if h.opt.Authorize != nil {
+		if err := h.opt.Authorize(req); err != nil {
+			h.audit(req, path, http.StatusForbidden, 0, err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+	
+	var (
+		status = http.StatusNoContent
+		files  int
+		err    error
+	)
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		h.serveMetadata(w, req, path)
+		return
+	case http.MethodPut:
+		status, err = h.put(req, path)
+		if err == nil {
+			files = 1
+		}
+	case http.MethodDelete:
+		err = h.delete(path)
+	case http.MethodPost:
+		files, err = h.replace(req, path)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	var ae *adminError
+	switch {
+	case errors.As(err, &ae):
+		status = ae.status
+	case os.IsNotExist(err):
+		status = http.StatusNotFound
+	case err != nil:
+		status = http.StatusConflict
+	}
+	h.audit(req, path, status, files, err)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+func (h *adminHandler) audit(req *http.Request, path string, status, files int, err error) {
+	if h.opt.Audit == nil || req.Method == http.MethodGet || req.Method == http.MethodHead {
+		return
+	}
+	h.opt.Audit(AuditEvent{
+		Time:       time.Now(),
+		Method:     req.Method,
+		Path:       path,
+		RemoteAddr: req.RemoteAddr,
+		Status:     status,
+		Files:      files,
+		Err:        err,
+	})
+}
+
+func (h *adminHandler) serveMetadata(w http.ResponseWriter, req *http.Request, path string) {
+	m, err := readMetadata(h.fs, path)
+	if os.IsNotExist(err) {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	if req.Method == http.MethodHead {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(m)
+}
+
+// readMetadata returns the metadata of path in fs, including directory entries.
+func readMetadata(fs http.FileSystem, path string) (*FileMetadata, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	m := fileMetadata(fi)
+	if fi.IsDir() {
+		fis, err := f.Readdir(0)
+		if err != nil {
+			return nil, err
+		}
+		m.Entries = []FileMetadata{}
+		for _, fi := range fis {
+			m.Entries = append(m.Entries, fileMetadata(fi))
+		}
+	}
+	return &m, nil
+}
+
+func fileMetadata(fi os.FileInfo) FileMetadata {
+	return FileMetadata{
+		Name:    fi.Name(),
+		Size:    fi.Size(),
+		Mode:    fi.Mode(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+		Meta:    Meta(fi),
+	}
+}
+
+func (h *adminHandler) put(req *http.Request, path string) (int, error) {
+	if path == "/" {
+		return 0, &adminError{http.StatusBadRequest, errors.New("cannot write to /")}
+	}
+	content, err := h.readBody(req)
+	if err != nil {
+		return 0, err
+	}
+	status := http.StatusNoContent
+	if _, err := h.fs.Stat(path); os.IsNotExist(err) {
+		status = http.StatusCreated
+	}
+	if err := h.fs.MkdirAll(pathpkg.Dir(path)); err != nil {
+		return 0, err
+	}
+	return status, h.fs.WriteFile(path, content)
+}
+
+func (h *adminHandler) delete(path string) error {
+	if _, err := h.fs.Stat(path); err != nil {
+		return err
+	}
+	return h.fs.RemoveAll(path)
+}
+
+func (h *adminHandler) replace(req *http.Request, path string) (int, error) {
+	body, err := h.readBody(req)
+	if err != nil {
+		return 0, err
+	}
+	staging := NewFS()
+	var files int
+	switch format := archiveFormat(req, body); format {
+	case "zip":
+		files, err = h.extractZip(staging, body)
+	case "tar", "tar.gz":
+		var r io.Reader = bytes.NewReader(body)
+		if format == "tar.gz" {
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return 0, &adminError{http.StatusBadRequest, err}
+			}
+			r = gr
+		}
+		files, err = h.extractTar(staging, r)
+	default:
+		return 0, &adminError{http.StatusUnsupportedMediaType, fmt.Errorf("unsupported archive type %q", req.Header.Get("Content-Type"))}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if fi, err := h.fs.Stat(path); err == nil && !fi.IsDir() {
+		return 0, &os.PathError{Op: "replace", Path: path, Err: ErrNotDir}
+	}
+	return files, h.fs.Replace(path, staging)
+}
+
+// readBody reads the request body, enforcing the size limit.
+func (h *adminHandler) readBody(req *http.Request) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(req.Body, h.opt.MaxSize+1))
+	if err != nil {
+		return nil, &adminError{http.StatusBadRequest, err}
+	}
+	if int64(len(b)) > h.opt.MaxSize {
+		return nil, &adminError{http.StatusRequestEntityTooLarge, errors.New("request body exceeds maximum size")}
+	}
+	return b, nil
+}
+
+// archiveFormat determines the archive format from the request
+// "format" query parameter, Content-Type header, or the body itself.
+func archiveFormat(req *http.Request, body []byte) string {
+	if format := req.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	switch req.Header.Get("Content-Type") {
+	case "application/zip":
+		return "zip"
+	case "application/x-tar":
+		return "tar"
+	case "application/gzip", "application/x-gzip":
+		return "tar.gz"
+	}
+	switch {
+	case bytes.HasPrefix(body, []byte("PK\x03\x04")):
+		return "zip"
+	case bytes.HasPrefix(body, []byte{0x1f, 0x8b}):
+		return "tar.gz"
+	case len(body) > 262 && string(body[257:262]) == "ustar":
+		return "tar"
+	}
+	return ""
+}
+
+func (h *adminHandler) extractZip(fs *FS, body []byte) (int, error) {
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return 0, &adminError{http.StatusBadRequest, err}
+	}
+	var files int
+	var total int64
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			if err := fs.MkdirAll(f.Name); err != nil {
+				return 0, &adminError{http.StatusBadRequest, err}
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return 0, &adminError{http.StatusBadRequest, err}
+		}
+		err = h.extractFile(fs, f.Name, f.Mode(), rc, &total)
+		_ = rc.Close()
+		if err != nil {
+			return 0, err
+		}
+		files++
+	}
+	return files, nil
+}
+
+func (h *adminHandler) extractTar(fs *FS, r io.Reader) (int, error) {
+	tr := tar.NewReader(r)
+	var files int
+	var total int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		} else if err != nil {
+			return 0, &adminError{http.StatusBadRequest, err}
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := fs.MkdirAll(hdr.Name); err != nil {
+				return 0, &adminError{http.StatusBadRequest, err}
+			}
+		case tar.TypeReg:
+			if err := h.extractFile(fs, hdr.Name, hdr.FileInfo().Mode(), tr, &total); err != nil {
+				return 0, err
+			}
+			files++
+		}
+	}
+}
+
+// extractFile writes a single archived file with the given mode to fs,
+// keeping track of the total extracted size.
+func (h *adminHandler) extractFile(fs *FS, name string, mode os.FileMode, r io.Reader, total *int64) error {
+	content, err := ioutil.ReadAll(io.LimitReader(r, h.opt.MaxExtractedSize-*total+1))
+	if err != nil {
+		return &adminError{http.StatusBadRequest, err}
+	}
+	*total += int64(len(content))
+	if *total > h.opt.MaxExtractedSize {
+		return &adminError{http.StatusRequestEntityTooLarge, errors.New("extracted archive exceeds maximum size")}
+	}
+	path := pathpkg.Clean("/" + strings.TrimPrefix(name, "./"))
+	if path == "/" {
+		return &adminError{http.StatusBadRequest, fmt.Errorf("invalid archive entry name %q", name)}
+	}
+	if err := fs.MkdirAll(pathpkg.Dir(path)); err != nil {
+		return &adminError{http.StatusBadRequest, err}
+	}
+	if err := fs.WriteFile(path, content); err != nil {
+		return &adminError{http.StatusBadRequest, err}
+	}
+	if mode.Perm() != 0 {
+		return fs.Chmod(path, mode)
+	}
+	return nil
+}
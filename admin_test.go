@@ -0,0 +1,112 @@
+package vfs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAdminHandler(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/site", "old.html", []byte("old"))
+	var events []AuditEvent
+	ts := httptest.NewServer(NewAdminHandler(fs, AdminOptions{
+		Authorize: func(req *http.Request) error {
+			if req.Header.Get("Authorization") != "secret" {
+				return errors.New("unauthorized")
+			}
+			return nil
+		},
+		MaxSize: 1 << 10,
+		Audit: func(e AuditEvent) {
+			events = append(events, e)
+		},
+	}))
+	defer ts.Close()
+	
+	do := func(method, path, contentType string, body []byte, wantStatus int) []byte {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "secret")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(resp.Body)
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("%s %s: got status %d, want %d: %s", method, path, resp.StatusCode, wantStatus, buf.Bytes())
+		}
+		return buf.Bytes()
+	}
+	
+	do("PUT", "/a/b/c.txt", "", []byte("hello"), http.StatusCreated)
+	do("PUT", "/a/b/c.txt", "", []byte("hello again"), http.StatusNoContent)
+	do("PUT", "/big.txt", "", make([]byte, 2<<10), http.StatusRequestEntityTooLarge)
+	
+	var m FileMetadata
+	if err := json.Unmarshal(do("GET", "/a/b", "", nil, http.StatusOK), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsDir || len(m.Entries) != 1 || m.Entries[0].Name != "c.txt" || m.Entries[0].Size != 11 {
+		t.Errorf("GET /a/b: got %+v", m)
+	}
+	
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for name, content := range map[string]string{"index.html": "new", "css/site.css": "body{}"} {
+		w, _ := zw.Create(name)
+		_, _ = w.Write([]byte(content))
+	}
+	_ = zw.Close()
+	do("POST", "/site", "application/zip", zipped.Bytes(), http.StatusNoContent)
+	if _, err := fs.Stat("/site/old.html"); !os.IsNotExist(err) {
+		t.Errorf("old file still exists after replacing subtree: %v", err)
+	}
+	if got := readFileT(t, fs, "/site/css/site.css"); got != "body{}" {
+		t.Errorf("got %q, want %q", got, "body{}")
+	}
+	do("POST", "/site", "text/plain", []byte("not an archive"), http.StatusUnsupportedMediaType)
+	
+	do("DELETE", "/a", "", nil, http.StatusNoContent)
+	do("DELETE", "/a", "", nil, http.StatusNotFound)
+	
+	resp, err := http.Post(ts.URL+"/x", "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unauthorized request: got status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+	
+	var statuses []int
+	for _, e := range events {
+		statuses = append(statuses, e.Status)
+	}
+	want := []int{201, 204, 413, 204, 415, 204, 404, 403}
+	if len(statuses) != len(want) {
+		t.Fatalf("audit statuses: got %v, want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("audit statuses: got %v, want %v", statuses, want)
+		}
+	}
+	if events[3].Files != 2 {
+		t.Errorf("audit files: got %d, want 2", events[3].Files)
+	}
+}
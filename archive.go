@@ -0,0 +1,308 @@
+package vfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strings"
+	
+	"github.com/shurcooL/httpgzip"
+)
+
+// ArchiveOptions configures archive creation.
+type ArchiveOptions struct {
+	// MaxSize is the maximum total uncompressed size of the files in an archive.
+	// If left zero, there is no limit.
+	MaxSize int64
+	
+	// Filter reports whether path should be included in the archive.
+	// Excluded directories are skipped together with their contents.
+	// If left nil, everything is included.
+	Filter func(path string, fi os.FileInfo) bool
+}
+
+// ErrArchiveTooLarge is returned when the files to archive exceed ArchiveOptions.MaxSize.
+var ErrArchiveTooLarge = errors.New("archive exceeds maximum size")
+
+// NewArchiveHandler returns a handler that serves the subtree of fs rooted at the
+// request URL path as an archive. The "format" query parameter selects between
+// "zip" (the default) and "tar.gz".
+func NewArchiveHandler(fs http.FileSystem, opt ArchiveOptions) http.Handler {
+	return &archiveHandler{fs: fs, opt: opt}
+}
+
+type archiveHandler struct {
+	fs  http.FileSystem
+	opt ArchiveOptions
+}
+
+func (h *archiveHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	root := pathpkg.Clean("/" + req.URL.Path)
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "zip"
+	}
+	var contentType string
+	switch format {
+	case "zip":
+		contentType = "application/zip"
+	case "tar.gz", "tgz":
+		format, contentType = "tar.gz", "application/gzip"
+	default:
+		http.Error(w, fmt.Sprintf("unsupported archive format %q", format), http.StatusBadRequest)
+		return
+	}
+	
+	entries, err := collectArchiveEntries(h.fs, root, h.opt)
+	switch {
+	case os.IsNotExist(err):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	case err == ErrArchiveTooLarge:
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, ErrNotDir):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	name := pathpkg.Base(root)
+	if name == "/" {
+		name = "root"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	if req.Method == http.MethodHead {
+		return
+	}
+	
+	if format == "zip" {
+		err = writeZip(w, h.fs, entries)
+	} else {
+		err = writeTarGz(w, h.fs, entries)
+	}
+	if err != nil {
+		// The response has already started, so the only way to signal
+		// the failure is to abort it and leave the archive truncated.
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// WriteZip writes the subtree of fs rooted at root to w as a zip archive.
+// Deflate data of files implementing httpgzip.GzipByter is reused as is.
+func WriteZip(w io.Writer, fs http.FileSystem, root string, opt ArchiveOptions) error {
+	entries, err := collectArchiveEntries(fs, root, opt)
+	if err != nil {
+		return err
+	}
+	return writeZip(w, fs, entries)
+}
+
+// WriteTarGz writes the subtree of fs rooted at root to w as a gzip compressed tar archive.
+func WriteTarGz(w io.Writer, fs http.FileSystem, root string, opt ArchiveOptions) error {
+	entries, err := collectArchiveEntries(fs, root, opt)
+	if err != nil {
+		return err
+	}
+	return writeTarGz(w, fs, entries)
+}
+
+// archiveEntry is a file or directory to be written to an archive.
+type archiveEntry struct {
+	path string // Path within fs.
+	name string // Name within the archive, relative to the archive root.
+	info os.FileInfo
+}
+
+// collectArchiveEntries walks the subtree at root and returns the entries to archive.
+// It's done up front so that the size limit can be enforced before anything is written.
+func collectArchiveEntries(fs http.FileSystem, root string, opt ArchiveOptions) ([]archiveEntry, error) {
+	root = pathpkg.Clean("/" + root)
+	var (
+		entries []archiveEntry
+		total   int64
+	)
+	err := Walk(fs, root, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			if !fi.IsDir() {
+				return &os.PathError{Op: "archive", Path: root, Err: ErrNotDir}
+			}
+			return nil
+		}
+		if opt.Filter != nil && !opt.Filter(path, fi) {
+			if fi.IsDir() {
+				return SkipDir
+			}
+			return nil
+		}
+		if !fi.IsDir() {
+			total += fi.Size()
+			if opt.MaxSize > 0 && total > opt.MaxSize {
+				return ErrArchiveTooLarge
+			}
+		}
+		entries = append(entries, archiveEntry{
+			path: path,
+			name: strings.TrimPrefix(strings.TrimPrefix(path, root), "/"),
+			info: fi,
+		})
+		return nil
+	})
+	return entries, err
+}
+
+func writeZip(w io.Writer, fs http.FileSystem, entries []archiveEntry) error {
+	zw := zip.NewWriter(w)
+	for _, e := range entries {
+		if e.info.IsDir() {
+			fh := &zip.FileHeader{Name: e.name + "/", Modified: e.info.ModTime()}
+			fh.SetMode(e.info.Mode())
+			if _, err := zw.CreateHeader(fh); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeZipFile(zw, fs, e); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, fs http.FileSystem, e archiveEntry) error {
+	f, err := fs.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	
+	fh := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: e.info.ModTime()}
+	fh.SetMode(e.info.Mode())
+	
+	if gf, ok := f.(httpgzip.GzipByter); ok {
+		if deflated, crc, ok := gzipDeflateData(gf.GzipBytes(), e.info.Size()); ok {
+			fh.CRC32 = crc
+			fh.CompressedSize64 = uint64(len(deflated))
+			fh.UncompressedSize64 = uint64(e.info.Size())
+			fw, err := zw.CreateRaw(fh)
+			if err != nil {
+				return err
+			}
+			_, err = fw.Write(deflated)
+			return err
+		}
+	}
+	if _, ok := f.(httpgzip.NotWorthGzipCompressing); ok {
+		fh.Method = zip.Store
+	}
+	fw, err := zw.CreateHeader(fh)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, f)
+	return err
+}
+
+func writeTarGz(w io.Writer, fs http.FileSystem, entries []archiveEntry) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		hdr, err := tar.FileInfoHeader(e.info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = e.name
+		if e.info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if e.info.IsDir() {
+			continue
+		}
+		if err := copyFile(tw, fs, e.path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func copyFile(w io.Writer, fs http.FileSystem, path string) error {
+	f, err := fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// gzipDeflateData extracts the raw deflate stream and CRC-32 from single member gzip data.
+// It reports false if b is not in a form that can be reused for a file of the given size.
+func gzipDeflateData(b []byte, size int64) (deflated []byte, crc uint32, ok bool) {
+	const (
+		flagHdrCrc  = 1 << 1
+		flagExtra   = 1 << 2
+		flagName    = 1 << 3
+		flagComment = 1 << 4
+	)
+	if len(b) < 18 || b[0] != 0x1f || b[1] != 0x8b || b[2] != 8 || size > 0xffffffff {
+		return nil, 0, false
+	}
+	flags := b[3]
+	i := 10
+	if flags&flagExtra != 0 {
+		if i+2 > len(b) {
+			return nil, 0, false
+		}
+		i += 2 + int(binary.LittleEndian.Uint16(b[i:]))
+	}
+	for _, flag := range []byte{flagName, flagComment} {
+		if flags&flag == 0 {
+			continue
+		}
+		for i < len(b) && b[i] != 0 {
+			i++
+		}
+		i++
+	}
+	if flags&flagHdrCrc != 0 {
+		i += 2
+	}
+	if i > len(b)-8 {
+		return nil, 0, false
+	}
+	trailer := b[len(b)-8:]
+	if int64(binary.LittleEndian.Uint32(trailer[4:])) != size {
+		// Either a multi-member stream or the size doesn't match, don't risk it.
+		return nil, 0, false
+	}
+	return b[i : len(b)-8], binary.LittleEndian.Uint32(trailer), true
+}
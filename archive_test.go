@@ -0,0 +1,137 @@
+package vfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestArchiveHandler(t *testing.T) {
+	fs := httpfs.New(mapfs.New(map[string]string{
+		"a/1.txt":      "one",
+		"a/b/2.txt":    "two",
+		"a/b/skip.map": "skipped",
+		"c/3.txt":      "three",
+	}))
+	ts := httptest.NewServer(NewArchiveHandler(fs, ArchiveOptions{
+		Filter: func(path string, fi os.FileInfo) bool {
+			return !strings.HasSuffix(path, ".map")
+		},
+	}))
+	defer ts.Close()
+	
+	b := get(t, ts.URL+"/a?format=zip", http.StatusOK)
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, _ := ioutil.ReadAll(rc)
+		_ = rc.Close()
+		got[f.Name] = string(c)
+	}
+	want := map[string]string{"1.txt": "one", "b/": "", "b/2.txt": "two"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("zip: got %v, want %v", got, want)
+	}
+	
+	b = get(t, ts.URL+"/?format=tar.gz", http.StatusOK)
+	gr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	wantNames := []string{"a/", "a/1.txt", "a/b/", "a/b/2.txt", "c/", "c/3.txt"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("tar.gz: got %v, want %v", names, wantNames)
+	}
+	
+	get(t, ts.URL+"/missing", http.StatusNotFound)
+	get(t, ts.URL+"/a?format=rar", http.StatusBadRequest)
+	get(t, ts.URL+"/c/3.txt", http.StatusBadRequest)
+	
+	limited := httptest.NewServer(NewArchiveHandler(fs, ArchiveOptions{MaxSize: 5}))
+	defer limited.Close()
+	get(t, limited.URL+"/a", http.StatusRequestEntityTooLarge)
+}
+
+func TestWriteZipReusesGzipBytes(t *testing.T) {
+	content := strings.Repeat("compress me ", 100)
+	fs := NewFS()
+	fs.Add("/", "file.txt", []byte(content))
+	
+	var buf bytes.Buffer
+	err := WriteZip(&buf, fs, "/", ArchiveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 1 {
+		t.Fatalf("got %d files, want 1", len(zr.File))
+	}
+	f := zr.File[0]
+	if want := uint64(len(fs.paths["/file.txt"].(*CompressedFileInfo).compressedContent) - 18); f.CompressedSize64 != want {
+		t.Errorf("compressed size %d, want %d reused from gzip bytes", f.CompressedSize64, want)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != content {
+		t.Errorf("got %q, want %q", b, content)
+	}
+}
+
+func get(t *testing.T, url string, wantStatus int) []byte {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("GET %s: got status %d, want %d: %s", url, resp.StatusCode, wantStatus, b)
+	}
+	return b
+}
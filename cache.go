@@ -0,0 +1,245 @@
+package vfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"sync"
+	"time"
+	
+	"github.com/shurcooL/httpgzip"
+)
+
+// CacheOptions configures a filesystem returned by Cached.
+type CacheOptions struct {
+	// TTL is how long cached files and directory listings are used without
+	// being revalidated. Once it expires, a file is revalidated by comparing
+	// the mod time and size reported by the underlying filesystem, and only
+	// read again if they changed, while a directory listing is read again.
+	// If left zero, entries are revalidated on every Open.
+	TTL time.Duration
+	
+	// MaxSize is the total size of compressed file contents kept in the cache.
+	// The least recently used files are evicted first, files larger than
+	// MaxSize are never cached.
+	// If left zero, it defaults to 64 MiB.
+	MaxSize int64
+}
+
+// Cached returns an http.FileSystem that caches the files, metadata and directory listings
+// of fs, so that reading them repeatedly is cheap. File contents are kept gzip compressed.
+// Directory listings are read again once their TTL expires, as the mod time of a
+// directory doesn't change when entries are added or removed on every filesystem,
+// such as an FS.
+func Cached(fs http.FileSystem, opt CacheOptions) http.FileSystem {
+	if opt.MaxSize == 0 {
+		opt.MaxSize = 64 << 20
+	}
+	return &cachedFS{
+		fs:      fs,
+		opt:     opt,
+		content: NewFS(),
+		entries: map[string]*cacheEntry{},
+		lru:     list.New(),
+	}
+}
+
+type cachedFS struct {
+	fs  http.FileSystem
+	opt CacheOptions
+	
+	mu      sync.Mutex
+	content *FS                    // Compressed file contents, see contentKey.
+	entries map[string]*cacheEntry // Keyed by path.
+	lru     *list.List             // Paths of files in content, most recently used first.
+	size    int64                  // Compressed size of files in content.
+}
+
+// cacheEntry is a cached file or directory.
+// Only checked and elem are modified once it's created, with mu held.
+type cacheEntry struct {
+	fi      os.FileInfo
+	entries []os.FileInfo // Only set for directories.
+	size    int64         // Compressed size, only set for files.
+	checked time.Time
+	elem    *list.Element // Position in lru, nil for directories and evicted files.
+}
+
+func (c *cachedFS) Open(name string) (http.File, error) {
+	path := pathpkg.Clean("/" + name)
+	c.mu.Lock()
+	e := c.entries[path]
+	fresh := e != nil && time.Since(e.checked) < c.opt.TTL
+	c.mu.Unlock()
+	if fresh {
+		if f, ok := c.serve(path, e); ok {
+			return f, nil
+		}
+	}
+	
+	f, err := c.fs.Open(path)
+	if err != nil {
+		c.forget(path)
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if e != nil && !fi.IsDir() && !e.fi.IsDir() && fi.ModTime().Equal(e.fi.ModTime()) && fi.Size() == e.fi.Size() {
+		c.mu.Lock()
+		e.checked = time.Now()
+		c.mu.Unlock()
+		if cf, ok := c.serve(path, e); ok {
+			_ = f.Close()
+			return cf, nil
+		}
+	}
+	
+	if !fi.IsDir() && fi.Size() > c.opt.MaxSize {
+		c.forget(path)
+		return f, nil
+	}
+	e, err = c.load(path, f, fi)
+	_ = f.Close()
+	if err != nil {
+		return nil, err
+	}
+	if cf, ok := c.serve(path, e); ok {
+		return cf, nil
+	}
+	// Evicted right away by a concurrent Open.
+	return c.fs.Open(path)
+}
+
+// serve returns the cached file or directory of e, if its content is still in the cache.
+func (c *cachedFS) serve(path string, e *cacheEntry) (http.File, bool) {
+	if e.fi.IsDir() {
+		d := &DirInfo{name: e.fi.Name(), modTime: e.fi.ModTime(), mode: e.fi.Mode().Perm(), entries: e.entries}
+		return &Dir{DirInfo: d, entries: d.entries}, true
+	}
+	c.mu.Lock()
+	if e.elem == nil {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.lru.MoveToFront(e.elem)
+	c.mu.Unlock()
+	f, err := c.content.Open(contentKey(path))
+	if err != nil {
+		return nil, false
+	}
+	return wrapFile(f, e.fi.Name(), nil), true
+}
+
+// load reads the file or directory f at path into a new cache entry.
+func (c *cachedFS) load(path string, f http.File, fi os.FileInfo) (*cacheEntry, error) {
+	e := &cacheEntry{fi: fi, checked: time.Now()}
+	if fi.IsDir() {
+		fis, err := f.Readdir(0)
+		if err != nil {
+			return nil, err
+		}
+		e.entries = sortedEntries(append([]os.FileInfo{}, fis...))
+		if err := c.store(path, e, nil); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	
+	gzipped, err := readGzipped(f)
+	if err != nil {
+		return nil, err
+	}
+	e.size = int64(len(gzipped))
+	err = c.store(path, e, &CompressedFileInfo{
+		name:              contentKey(path)[1:],
+		modTime:           fi.ModTime(),
+		mode:              fi.Mode().Perm(),
+		uncompressedSize:  fi.Size(),
+		compressedContent: gzipped,
+		meta:              Meta(fi),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// store adds e, with the content of files, to the cache, replacing the previous
+// entry for path, and evicts the least recently used files to stay within MaxSize.
+func (c *cachedFS) store(path string, e *cacheEntry, content *CompressedFileInfo) error {
+	c.mu.Lock()
+	defer func() {
+		c.mu.Unlock()
+	}()
+	
+	c.remove(path)
+	if content != nil {
+		if err := c.content.insert(contentKey(path), content); err != nil {
+			return err
+		}
+	}
+	c.entries[path] = e
+	if e.fi.IsDir() {
+		return nil
+	}
+	e.elem = c.lru.PushFront(path)
+	c.size += e.size
+	for c.size > c.opt.MaxSize {
+		c.remove(c.lru.Back().Value.(string))
+	}
+	return nil
+}
+
+// forget removes path from the cache.
+func (c *cachedFS) forget(path string) {
+	c.mu.Lock()
+	c.remove(path)
+	c.mu.Unlock()
+}
+
+// remove removes path from the cache. mu must be held.
+func (c *cachedFS) remove(path string) {
+	e, ok := c.entries[path]
+	if !ok {
+		return
+	}
+	delete(c.entries, path)
+	if e.elem != nil {
+		c.lru.Remove(e.elem)
+		e.elem = nil
+		c.size -= e.size
+		_ = c.content.RemoveAll(contentKey(path))
+	}
+}
+
+// contentKey returns the path in opt.Content holding the content of path.
+// Contents are kept in a single directory so files and directories
+// replacing each other at the same path don't conflict.
+func contentKey(path string) string {
+	return "/" + url.PathEscape(path)
+}
+
+// readGzipped returns the gzip compressed content of f,
+// compressing it only if f doesn't provide its compressed bytes.
+func readGzipped(f http.File) ([]byte, error) {
+	if gf, ok := f.(httpgzip.GzipByter); ok {
+		return gf.GzipBytes(), nil
+	}
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := io.Copy(gw, f); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
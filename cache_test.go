@@ -0,0 +1,153 @@
+package vfs
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingFS counts the files opened and read in the filesystem it wraps.
+type countingFS struct {
+	http.FileSystem
+	opens, reads int64
+}
+
+func (c *countingFS) Open(name string) (http.File, error) {
+	atomic.AddInt64(&c.opens, 1)
+	f, err := c.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &countingFile{File: f, fs: c}, nil
+}
+
+type countingFile struct {
+	http.File
+	fs *countingFS
+}
+
+func (f *countingFile) Read(p []byte) (int, error) {
+	atomic.AddInt64(&f.fs.reads, 1)
+	return f.File.Read(p)
+}
+
+func TestCached(t *testing.T) {
+	src := NewFS()
+	src.Add("/", "a.txt", []byte("aaa"))
+	src.Add("/dir", "b.txt", []byte("bbb"))
+	counting := &countingFS{FileSystem: src}
+	fs := Cached(counting, CacheOptions{TTL: time.Hour})
+	
+	for i := 0; i < 3; i++ {
+		if got := readFileT(t, fs, "/a.txt"); got != "aaa" {
+			t.Errorf("got %q", got)
+		}
+		if got := readDirNamesT(t, fs, "/"); got != "a.txt,dir" {
+			t.Errorf("Readdir: got %q", got)
+		}
+	}
+	if counting.opens != 2 {
+		t.Errorf("got %d opens of the underlying filesystem, want 2", counting.opens)
+	}
+	if _, err := fs.Open("/missing"); !os.IsNotExist(err) {
+		t.Errorf("got %v, want not exist error", err)
+	}
+}
+
+func TestCachedRevalidation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "page.html")
+	if err := ioutil.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	proxy, err := NewProxy(dir, ProxyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingFS{FileSystem: proxy}
+	fs := Cached(counting, CacheOptions{})
+	
+	readFileT(t, fs, "/page.html")
+	reads := counting.reads
+	if got := readFileT(t, fs, "/page.html"); got != "v1" {
+		t.Errorf("got %q", got)
+	}
+	if counting.reads != reads {
+		t.Error("unchanged file was read again")
+	}
+	
+	if err := ioutil.WriteFile(path, []byte("v2!"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFileT(t, fs, "/page.html"); got != "v2!" {
+		t.Errorf("got %q after change, want %q", got, "v2!")
+	}
+	if got := readDirNamesT(t, fs, "/"); got != "page.html" {
+		t.Errorf("Readdir: got %q", got)
+	}
+}
+
+func TestCachedDirRevalidation(t *testing.T) {
+	src := NewFS()
+	src.Add("/dir", "a.txt", []byte("a"))
+	fs := Cached(src, CacheOptions{})
+	
+	if got := readDirNamesT(t, fs, "/dir"); got != "a.txt" {
+		t.Errorf("Readdir: got %q", got)
+	}
+	// Adding a file doesn't change the mod time of the directory.
+	src.Add("/dir", "b.txt", []byte("b"))
+	if got := readDirNamesT(t, fs, "/dir"); got != "a.txt,b.txt" {
+		t.Errorf("Readdir after adding a file: got %q, want %q", got, "a.txt,b.txt")
+	}
+	if err := src.RemoveAll("/dir/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDirNamesT(t, fs, "/dir"); got != "b.txt" {
+		t.Errorf("Readdir after removing a file: got %q, want %q", got, "b.txt")
+	}
+	
+	cached := Cached(src, CacheOptions{TTL: time.Hour})
+	readDirNamesT(t, cached, "/dir")
+	src.Add("/dir", "c.txt", []byte("c"))
+	if got := readDirNamesT(t, cached, "/dir"); got != "b.txt" {
+		t.Errorf("Readdir within TTL: got %q, want the cached %q", got, "b.txt")
+	}
+}
+
+func TestCachedMaxSize(t *testing.T) {
+	src := NewFS()
+	var size int64
+	for _, name := range []string{"a", "b", "c"} {
+		// Random content, so it doesn't compress.
+		content := make([]byte, 1000)
+		rand.New(rand.NewSource(int64(name[0]))).Read(content)
+		src.Add("/", name, content)
+		size = int64(len(newCompressedFileInfo(name, content).compressedContent))
+	}
+	counting := &countingFS{FileSystem: src}
+	fs := Cached(counting, CacheOptions{TTL: time.Hour, MaxSize: 2*size + size/2})
+	
+	readFileT(t, fs, "/a")
+	readFileT(t, fs, "/b")
+	readFileT(t, fs, "/a")
+	opens := counting.opens
+	readFileT(t, fs, "/c") // Evicts b, the least recently used.
+	readFileT(t, fs, "/a")
+	if got := counting.opens - opens; got != 1 {
+		t.Errorf("got %d opens, want 1", got)
+	}
+	readFileT(t, fs, "/b")
+	if got := counting.opens - opens; got != 2 {
+		t.Errorf("got %d opens, want 2 after reading evicted file", got)
+	}
+}
@@ -0,0 +1,148 @@
+package vfs
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	pathpkg "path"
+	"strings"
+)
+
+// ContentTyper is implemented by files and file infos that know the MIME type of
+// their content, such as those of an FS and of the code generated by Generate.
+type ContentTyper interface {
+	ContentType() string
+}
+
+// ContentTypeRule sets the MIME type of the files matching a pattern.
+type ContentTypeRule struct {
+	// Pattern is a gitignore style pattern, as described by FilterRules.Exclude.
+	Pattern string
+	
+	// ContentType is the MIME type of the matching files.
+	ContentType string
+}
+
+// contentTypeRules are compiled content type rules, the first matching rule applies.
+type contentTypeRules []compiledContentTypeRule
+
+type compiledContentTypeRule struct {
+	m           *matcher
+	contentType string
+}
+
+func compileContentTypeRules(rules []ContentTypeRule) (contentTypeRules, error) {
+	var compiled contentTypeRules
+	for _, rule := range rules {
+		m, err := compileMatcher([]string{rule.Pattern})
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, compiledContentTypeRule{m: m, contentType: rule.ContentType})
+	}
+	return compiled, nil
+}
+
+// match returns the MIME type set by the first rule matching the file at path.
+func (rules contentTypeRules) match(path string) (string, bool) {
+	for _, rule := range rules {
+		if rule.m.excluded(path, false) {
+			return rule.contentType, true
+		}
+	}
+	return "", false
+}
+
+// sniffLen is the number of leading bytes http.DetectContentType considers.
+const sniffLen = 512
+
+// detectContentType returns the MIME type of the file named name from its extension,
+// or by sniffing the start of its content, read from r, if the extension is unknown.
+// At most sniffLen bytes are read.
+func detectContentType(name string, r io.Reader) (string, error) {
+	if ctype := mime.TypeByExtension(pathpkg.Ext(name)); ctype != "" {
+		return ctype, nil
+	}
+	head, err := ioutil.ReadAll(io.LimitReader(r, sniffLen))
+	if err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head), nil
+}
+
+// sniffContentType returns the MIME type of the file named name, reading the
+// beginning of its content from r if the extension is unknown.
+// r is left at its start.
+func sniffContentType(name string, r io.ReadSeeker) (string, error) {
+	if ctype := mime.TypeByExtension(pathpkg.Ext(name)); ctype != "" {
+		return ctype, nil
+	}
+	head, err := readHead(r, sniffLen)
+	if err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head), nil
+}
+
+// readHead returns up to n bytes from the start of r, leaving r at its start.
+func readHead(r io.ReadSeeker, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// FileServer returns a handler like http.FileServer, which sets the Content-Type
+// of files that implement ContentTyper, or have it in their FileMeta, rather
+// than detecting it on every request.
+func FileServer(fs http.FileSystem) http.Handler {
+	return &fileServer{fs: fs, fallback: http.FileServer(fs)}
+}
+
+type fileServer struct {
+	fs       http.FileSystem
+	fallback http.Handler
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	// Directories, index pages and non canonical paths involve
+	// redirects and listings, leave them to http.FileServer.
+	if path != pathpkg.Clean(path) || strings.HasSuffix(path, "/index.html") {
+		s.fallback.ServeHTTP(w, req)
+		return
+	}
+	f, err := s.fs.Open(path)
+	if err != nil {
+		s.fallback.ServeHTTP(w, req)
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		s.fallback.ServeHTTP(w, req)
+		return
+	}
+	var ctype string
+	if ct, ok := f.(ContentTyper); ok {
+		ctype = ct.ContentType()
+	} else if m := Meta(fi); m != nil {
+		ctype = m.ContentType
+	}
+	if ctype != "" {
+		w.Header().Set("Content-Type", ctype)
+	}
+	http.ServeContent(w, req, fi.Name(), fi.ModTime(), f)
+}
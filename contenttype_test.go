@@ -0,0 +1,60 @@
+package vfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFileServer(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/", "page", []byte("<!DOCTYPE html><p>Hello.</p>"))
+	fs.Add("/", "site.css", []byte("p { color: red; }"))
+	if err := fs.AddFile("/data.bin", []byte("{}"), AddFileOptions{ContentType: "application/json"}); err != nil {
+		t.Fatal(err)
+	}
+	fs.Add("/docs", "index.html", []byte("<p>Docs.</p>"))
+	
+	srv := httptest.NewServer(FileServer(fs))
+	defer srv.Close()
+	
+	for _, test := range []struct {
+		path       string
+		wantStatus int
+		wantType   string
+	}{
+		{"/page", http.StatusOK, "text/html; charset=utf-8"},
+		{"/site.css", http.StatusOK, "text/css; charset=utf-8"},
+		{"/data.bin", http.StatusOK, "application/json"},
+		{"/docs/", http.StatusOK, "text/html; charset=utf-8"},
+		{"/missing", http.StatusNotFound, "text/plain; charset=utf-8"},
+	} {
+		resp, err := http.Get(srv.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != test.wantStatus || resp.Header.Get("Content-Type") != test.wantType {
+			t.Errorf("GET %s: got %v %q, want %v %q", test.path, resp.StatusCode, resp.Header.Get("Content-Type"), test.wantStatus, test.wantType)
+		}
+	}
+}
+
+func TestContentTypeRules(t *testing.T) {
+	rules, err := compileContentTypeRules([]ContentTypeRule{
+		{Pattern: "/legacy/*.js", ContentType: "application/x-legacy"},
+		{Pattern: "*.js", ContentType: "application/javascript"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"/legacy/a.js": "application/x-legacy",
+		"/app/b.js":    "application/javascript",
+		"/c.css":       "",
+	} {
+		if got, _ := rules.match(path); got != want {
+			t.Errorf("match %s: got %q, want %q", path, got, want)
+		}
+	}
+}
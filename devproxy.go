@@ -0,0 +1,260 @@
+package vfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DevProxyOptions configures a DevProxy.
+type DevProxyOptions struct {
+	ProxyOptions
+	
+	// PollInterval is how often the directory tree is scanned for changes.
+	// If left zero, it defaults to 500 milliseconds.
+	PollInterval time.Duration
+}
+
+// ChangeOp is the kind of change reported by a ChangeEvent.
+type ChangeOp int
+
+// Kinds of changes.
+const (
+	Created ChangeOp = iota + 1
+	Modified
+	Removed
+)
+
+func (op ChangeOp) String() string {
+	switch op {
+	case Created:
+		return "created"
+	case Modified:
+		return "modified"
+	case Removed:
+		return "removed"
+	default:
+		return fmt.Sprintf("ChangeOp(%d)", int(op))
+	}
+}
+
+// ChangeEvent describes a change to a file or directory watched by a DevProxy.
+type ChangeEvent struct {
+	Path    string
+	Op      ChangeOp
+	Version uint64 // Version of the DevProxy after the change.
+}
+
+// DevProxy is a NewProxy filesystem for development that watches its directory
+// tree by polling, and reports changes so that caches built on top of it can
+// be invalidated and browsers reloaded.
+type DevProxy struct {
+	http.FileSystem
+	
+	interval  time.Duration
+	done      chan struct{}
+	closeOnce sync.Once
+	
+	scanMu sync.Mutex // Serializes scans, so that they're applied in order.
+	
+	mu          sync.Mutex
+	version     uint64
+	state       map[string]fileState
+	subscribers map[chan ChangeEvent]struct{}
+}
+
+// fileState is what's compared between scans to detect changes.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	isDir   bool
+}
+
+// NewDevProxy returns a DevProxy serving dir, and starts watching it.
+// Close must be called to stop watching.
+func NewDevProxy(dir string, opt DevProxyOptions) (*DevProxy, error) {
+	fs, err := NewProxy(dir, opt.ProxyOptions)
+	if err != nil {
+		return nil, err
+	}
+	if opt.PollInterval == 0 {
+		opt.PollInterval = 500 * time.Millisecond
+	}
+	p := &DevProxy{
+		FileSystem:  fs,
+		interval:    opt.PollInterval,
+		done:        make(chan struct{}),
+		subscribers: map[chan ChangeEvent]struct{}{},
+	}
+	p.state, err = p.scan()
+	if err != nil {
+		return nil, err
+	}
+	go p.watch()
+	return p, nil
+}
+
+// Version returns a number that increases every time a change is detected.
+func (p *DevProxy) Version() uint64 {
+	p.mu.Lock()
+	defer func() {
+		p.mu.Unlock()
+	}()
+	
+	return p.version
+}
+
+// Subscribe returns a channel receiving change events, and a function that
+// cancels the subscription. Events are dropped if the channel is not drained
+// quickly enough, subscribers that need to catch up can compare Version.
+func (p *DevProxy) Subscribe() (<-chan ChangeEvent, func()) {
+	ch := make(chan ChangeEvent, 64)
+	p.mu.Lock()
+	p.subscribers[ch] = struct{}{}
+	p.mu.Unlock()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			p.mu.Lock()
+			delete(p.subscribers, ch)
+			p.mu.Unlock()
+		})
+	}
+}
+
+// Close stops watching the directory tree.
+func (p *DevProxy) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	return nil
+}
+
+// Rescan checks the directory tree for changes immediately,
+// and returns the changes it found.
+func (p *DevProxy) Rescan() ([]ChangeEvent, error) {
+	p.scanMu.Lock()
+	defer func() {
+		p.scanMu.Unlock()
+	}()
+	
+	state, err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+	
+	p.mu.Lock()
+	defer func() {
+		p.mu.Unlock()
+	}()
+	
+	var events []ChangeEvent
+	for path, s := range state {
+		old, ok := p.state[path]
+		switch {
+		case !ok:
+			events = append(events, ChangeEvent{Path: path, Op: Created})
+		case old != s:
+			events = append(events, ChangeEvent{Path: path, Op: Modified})
+		}
+	}
+	for path := range p.state {
+		if _, ok := state[path]; !ok {
+			events = append(events, ChangeEvent{Path: path, Op: Removed})
+		}
+	}
+	p.state = state
+	if len(events) == 0 {
+		return nil, nil
+	}
+	
+	p.version++
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+	for i := range events {
+		events[i].Version = p.version
+		for ch := range p.subscribers {
+			select {
+			case ch <- events[i]:
+			default:
+			}
+		}
+	}
+	return events, nil
+}
+
+func (p *DevProxy) watch() {
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-t.C:
+			// Errors are transient while files are being edited, the next scan will tell.
+			_, _ = p.Rescan()
+		}
+	}
+}
+
+func (p *DevProxy) scan() (map[string]fileState, error) {
+	state := map[string]fileState{}
+	err := Walk(p.FileSystem, "/", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Files may disappear between listing and opening them.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		s := fileState{isDir: fi.IsDir()}
+		if !s.isDir {
+			s.modTime, s.size = fi.ModTime(), fi.Size()
+		}
+		state[path] = s
+		return nil
+	})
+	return state, err
+}
+
+// LiveReloadHandler returns a handler that streams change events to browsers
+// as server-sent events. Each event has type "change" and JSON data with the
+// path, op and version. The current version is sent as a "version" event
+// when a client connects, so it can tell whether it missed changes.
+func (p *DevProxy) LiveReloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		events, cancel := p.Subscribe()
+		defer cancel()
+		
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = fmt.Fprintf(w, "event: version\ndata: %d\n\n", p.Version())
+		flusher.Flush()
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case <-p.done:
+				return
+			case e := <-events:
+				data, _ := json.Marshal(struct {
+					Path    string `json:"path"`
+					Op      string `json:"op"`
+					Version uint64 `json:"version"`
+				}{e.Path, e.Op.String(), e.Version})
+				if _, err := fmt.Fprintf(w, "event: change\ndata: %s\n\n", data); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
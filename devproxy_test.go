@@ -0,0 +1,106 @@
+package vfs
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDevProxy(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.tmpl"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewDevProxy(dir, DevProxyOptions{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	events, cancel := p.Subscribe()
+	defer cancel()
+	
+	if err := os.WriteFile(filepath.Join(dir, "a.tmpl"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.tmpl"), []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.Rescan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ChangeEvent{{"/a.tmpl", Modified, 1}, {"/b.tmpl", Created, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if e := <-events; e != want[0] {
+		t.Errorf("subscriber got %v, want %v", e, want[0])
+	}
+	if got := readFileT(t, p, "/a.tmpl"); got != "changed" {
+		t.Errorf("got %q, want %q", got, "changed")
+	}
+	
+	ts := httptest.NewServer(p.LiveReloadHandler())
+	defer ts.Close()
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+	if got, want := readEvent(), "event: version\ndata: 1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if err := os.Remove(filepath.Join(dir, "b.tmpl")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Rescan(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readEvent(), "event: change\ndata: {\"path\":\"/b.tmpl\",\"op\":\"removed\",\"version\":2}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	
+	// Overlapping rescans are applied in order, so a change is reported once.
+	if err := os.WriteFile(filepath.Join(dir, "c.tmpl"), []byte("c"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	var reported int32
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			events, err := p.Rescan()
+			if err != nil {
+				t.Error(err)
+			}
+			atomic.AddInt32(&reported, int32(len(events)))
+		}()
+	}
+	wg.Wait()
+	if reported != 1 || p.Version() != 3 {
+		t.Errorf("concurrent rescans reported %d events and version %d, want 1 and 3", reported, p.Version())
+	}
+}
@@ -0,0 +1,12 @@
+package vfs
+
+// SetTestHookWriteFile sets the function called by the goroutines of Generate
+// writing the definitions of files before they start, and returns a function
+// restoring the previous one.
+func SetTestHookWriteFile(f func()) (restore func()) {
+	old := testHookWriteFile
+	testHookWriteFile = f
+	return func() {
+		testHookWriteFile = old
+	}
+}
@@ -0,0 +1,227 @@
+package vfs
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strings"
+	"sync"
+)
+
+// FilterRules describes the files and directories hidden by Filter.
+type FilterRules struct {
+	// Exclude is a list of gitignore style patterns. A path is hidden if the
+	// last pattern matching it excludes it. Patterns starting with "!" are
+	// negated and include again what previous patterns excluded, except
+	// below an excluded directory. Patterns ending with "/" only match directories.
+	// Patterns containing "/" elsewhere are relative to the root, others match
+	// names at any depth. "**" matches any number of directories. Blank
+	// patterns and patterns starting with "#" are ignored.
+	Exclude []string
+	
+	// Keep, if set, is called for each file that isn't excluded by a pattern,
+	// and hides it when it returns false. It's not called for directories.
+	Keep func(path string, fi os.FileInfo) bool
+	
+	// PruneEmptyDirs hides directories left without visible files, at any depth,
+	// because the other rules hide their contents. Directories that have no entries
+	// to begin with are kept, and the root directory is never hidden. Whether a directory is empty is
+	// determined the first time it's needed and remembered, so it's meant
+	// for filesystems that don't change, such as the input of Generate.
+	PruneEmptyDirs bool
+}
+
+// Filter returns an http.FileSystem that hides the files and directories of fs
+// described by rules. Hidden paths can't be opened and aren't listed by Readdir,
+// so Walk and Generate don't see them either.
+// Opened files keep the optional GzipBytes, NotWorthGzipCompressing and ContentType methods.
+func Filter(fs http.FileSystem, rules FilterRules) (http.FileSystem, error) {
+	m, err := compileMatcher(rules.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return &filterFS{fs: fs, rules: rules, m: m}, nil
+}
+
+type filterFS struct {
+	fs      http.FileSystem
+	rules   FilterRules
+	m       *matcher
+	skipped func(path string) // Called with hidden paths met by Open and Readdir, if set.
+	
+	mu    sync.Mutex
+	empty map[string]bool // Whether directories have no visible files, see isEmpty.
+}
+
+func (f *filterFS) Open(name string) (http.File, error) {
+	path := pathpkg.Clean("/" + name)
+	for p := pathpkg.Dir(path); p != "/"; p = pathpkg.Dir(p) {
+		if f.m.excluded(p, true) {
+			return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+		}
+	}
+	file, err := f.fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	if path != "/" && !f.visible(path, fi) {
+		_ = file.Close()
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return wrapFile(file, fi.Name(), func(fis []os.FileInfo) []os.FileInfo {
+		entries := []os.FileInfo{}
+		for _, fi := range fis {
+			if f.visible(pathpkg.Join(path, fi.Name()), fi) {
+				entries = append(entries, fi)
+			}
+		}
+		return entries
+	}), nil
+}
+
+// visible reports whether path, whose parents are visible, is visible,
+// and reports it to skipped if it isn't.
+func (f *filterFS) visible(path string, fi os.FileInfo) bool {
+	if f.hidden(path, fi) {
+		if f.skipped != nil {
+			f.skipped(path)
+		}
+		return false
+	}
+	return true
+}
+
+// hidden reports whether path, whose parents are visible, is hidden.
+func (f *filterFS) hidden(path string, fi os.FileInfo) bool {
+	switch {
+	case f.m.excluded(path, fi.IsDir()):
+		return true
+	case !fi.IsDir():
+		return f.rules.Keep != nil && !f.rules.Keep(path, fi)
+	default:
+		return f.rules.PruneEmptyDirs && f.isEmpty(path)
+	}
+}
+
+// isEmpty reports whether dir has entries, but no visible files at any depth.
+// Results are remembered, so that each directory is read once.
+func (f *filterFS) isEmpty(dir string) bool {
+	f.mu.Lock()
+	empty, ok := f.empty[dir]
+	f.mu.Unlock()
+	if ok {
+		return empty
+	}
+	
+	fis, err := readDir(f.fs, dir)
+	if err != nil {
+		// Keep it, so the error surfaces when dir is opened.
+		return false
+	}
+	empty = len(fis) > 0
+	for _, fi := range fis {
+		if !f.hidden(pathpkg.Join(dir, fi.Name()), fi) {
+			empty = false
+			break
+		}
+	}
+	
+	f.mu.Lock()
+	if f.empty == nil {
+		f.empty = map[string]bool{}
+	}
+	f.empty[dir] = empty
+	f.mu.Unlock()
+	return empty
+}
+
+// matcher matches paths against gitignore style patterns, see FilterRules.Exclude.
+type matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	elems   []string
+	negate  bool
+	dirOnly bool
+}
+
+func compileMatcher(patterns []string) (*matcher, error) {
+	m := &matcher{}
+	for _, orig := range patterns {
+		s := strings.TrimSpace(orig)
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		var p pattern
+		if strings.HasPrefix(s, "!") {
+			p.negate, s = true, s[1:]
+		}
+		if strings.HasSuffix(s, "/") {
+			p.dirOnly, s = true, strings.TrimSuffix(s, "/")
+		}
+		if !strings.Contains(s, "/") {
+			s = "**/" + s
+		}
+		for _, elem := range strings.Split(strings.TrimPrefix(s, "/"), "/") {
+			if _, err := pathpkg.Match(elem, ""); err != nil || elem == "" {
+				return nil, fmt.Errorf("invalid pattern %q", orig)
+			}
+			p.elems = append(p.elems, elem)
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+// excluded reports whether the patterns exclude path, a slash separated path starting with "/".
+// It doesn't consider the parents of path.
+func (m *matcher) excluded(path string, isDir bool) bool {
+	if path == "/" {
+		return false
+	}
+	elems := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	excluded := false
+	for _, p := range m.patterns {
+		// Skip patterns that can't change the result.
+		if p.dirOnly && !isDir || p.negate != excluded {
+			continue
+		}
+		if matchElems(p.elems, elems) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+func matchElems(pattern, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// A trailing "**" matches everything inside, but not the directory itself.
+			min := 0
+			if len(pattern) == 1 {
+				min = 1
+			}
+			for i := min; i <= len(elems); i++ {
+				if matchElems(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, _ := pathpkg.Match(pattern[0], elems[0]); !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
@@ -0,0 +1,162 @@
+package vfs
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/", "index.html", []byte("index"))
+	fs.Add("/", "debug.log", []byte("log"))
+	fs.Add("/", "important.log", []byte("keep me"))
+	fs.Add("/src", "main.go", []byte("package main"))
+	fs.Add("/src/internal/testdata", "big.bin", []byte(strings.Repeat("x", 100)))
+	fs.Add("/assets/img", "logo.png", []byte("png"))
+	fs.Add("/assets/img/.cache", "thumb.png", []byte("png"))
+	fs.Add("/build", "out.js", []byte("out"))
+	fs.Add("/docs/drafts", "wip.md", []byte("wip"))
+	
+	filtered, err := Filter(fs, FilterRules{
+		Exclude: []string{
+			"# Comments and blank lines are ignored.",
+			"",
+			"*.log",
+			"!important.log",
+			"/build/",
+			"**/.cache",
+			"docs/drafts/**",
+			"*.go",
+			"!/build/out.js",
+		},
+		Keep: func(path string, fi os.FileInfo) bool {
+			return fi.Size() < 100
+		},
+		PruneEmptyDirs: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	for dir, want := range map[string]string{
+		"/":           "assets,important.log,index.html",
+		"/assets/img": "logo.png",
+	} {
+		if got := readDirNamesT(t, filtered, dir); got != want {
+			t.Errorf("Readdir(%q): got %q, want %q", dir, got, want)
+		}
+	}
+	for _, path := range []string{"/debug.log", "/build/out.js", "/assets/img/.cache/thumb.png", "/src", "/docs", "/src/internal/testdata/big.bin"} {
+		if _, err := filtered.Open(path); !os.IsNotExist(err) {
+			t.Errorf("Open(%q): got %v, want not exist error", path, err)
+		}
+	}
+	if got := readFileT(t, filtered, "/important.log"); got != "keep me" {
+		t.Errorf("got %q", got)
+	}
+	f, err := filtered.Open("/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct, ok := f.(ContentTyper); !ok || ct.ContentType() != "text/html; charset=utf-8" {
+		t.Errorf("opened file doesn't keep its content type: %T", f)
+	}
+	_ = f.Close()
+	
+	var walked []string
+	err = Walk(filtered, "/", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/,/assets,/assets/img,/assets/img/logo.png,/important.log,/index.html"
+	if got := strings.Join(walked, ","); got != want {
+		t.Errorf("Walk: got %q, want %q", got, want)
+	}
+	
+	if _, err := Filter(fs, FilterRules{Exclude: []string{"[a-"}}); err == nil {
+		t.Error("got nil error for invalid pattern")
+	}
+}
+
+func TestFilterPruneEmptyDirs(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/a/b/c/d", "file.txt", []byte("file"))
+	fs.Add("/a/b/c/d", "file.log", []byte("log"))
+	fs.Add("/e/f/g", "only.log", []byte("log"))
+	if err := fs.MkdirAll("/h/empty"); err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingFS{FileSystem: fs}
+	filtered, err := Filter(counting, FilterRules{Exclude: []string{"*.log"}, PruneEmptyDirs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var skipped []string
+	filtered.(*filterFS).skipped = func(path string) { skipped = append(skipped, path) }
+	
+	walk := func() (string, int64) {
+		opens := counting.opens
+		var walked []string
+		err := Walk(filtered, "/", func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			walked = append(walked, path)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(walked, ","), counting.opens - opens
+	}
+	got, firstOpens := walk()
+	// Directories without entries are kept, unlike those emptied by the rules.
+	if want := "/,/a,/a/b,/a/b/c,/a/b/c/d,/a/b/c/d/file.txt,/h,/h/empty"; got != want {
+		t.Errorf("Walk: got %q, want %q", got, want)
+	}
+	// Paths inside pruned directories aren't reported, as the walk never gets there.
+	sort.Strings(skipped)
+	if got, want := strings.Join(skipped, ","), "/a/b/c/d/file.log,/e"; got != want {
+		t.Errorf("skipped %q, want %q", got, want)
+	}
+	// The 9 directories below the root are read once to find out whether they're empty.
+	if _, secondOpens := walk(); firstOpens-secondOpens != 9 {
+		t.Errorf("first walk opened %d files, second walk %d, want 9 more reading directories", firstOpens, secondOpens)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	for _, tc := range []struct {
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{[]string{"*.txt"}, "/a/b/c.txt", false, true},
+		{[]string{"/*.txt"}, "/a/c.txt", false, false},
+		{[]string{"a/**/c"}, "/a/c", true, true},
+		{[]string{"a/**/c"}, "/a/x/y/c", true, true},
+		{[]string{"a/**"}, "/a", true, false},
+		{[]string{"a/**"}, "/a/b", false, true},
+		{[]string{"tmp/"}, "/x/tmp", false, false},
+		{[]string{"tmp/"}, "/x/tmp", true, true},
+		{[]string{"*.txt", "!keep.txt"}, "/keep.txt", false, false},
+		{[]string{"*.txt", "!keep.txt", "*"}, "/keep.txt", false, true},
+	} {
+		m, err := compileMatcher(tc.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.excluded(tc.path, tc.isDir); got != tc.want {
+			t.Errorf("%q excluded(%q): got %v, want %v", tc.patterns, tc.path, got, tc.want)
+		}
+	}
+}
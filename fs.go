@@ -3,28 +3,50 @@ package vfs
 import (
 	"bytes"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	pathpkg "path"
-	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
 func NewFS() *FS {
 	return &FS{
-		paths: map[string]interface{}{},
+		paths:    map[string]interface{}{},
+		versions: map[string]uint64{},
+		links:    map[*blob]int{},
 	}
 }
 
+// NewFSWithOptions returns an empty FS that matches paths to names as configured by opt.
+func NewFSWithOptions(opt FSOptions) *FS {
+	fs := NewFS()
+	fs.key = opt.keyFunc()
+	return fs
+}
+
+// FS is an in-memory http.FileSystem whose contents can be changed at runtime.
+// File contents are stored gzip compressed.
 type FS struct {
-	lock  sync.Mutex
-	paths map[string]interface{}
+	lock     sync.Mutex
+	paths    map[string]interface{}
+	version  uint64            // Incremented on every change.
+	versions map[string]uint64 // Version at which each path last changed.
+	links    map[*blob]int     // Number of paths referencing each blob.
+	
+	// key maps paths to the keys they're matched by, nil for exact matching.
+	key   func(string) string
+	index map[string]string // Paths keyed by key, only set if key is not nil.
 }
 
+// Paths returns the file and directory definitions of fs, keyed by path.
+// Files created with Link have definitions of their own, sharing the stored content.
 func (fs *FS) Paths() map[string]interface{} {
 	fs.lock.Lock()
 	defer func() {
@@ -39,101 +61,555 @@ func (fs *FS) Paths() map[string]interface{} {
 	return m
 }
 
-func (fs *FS) Add(dir, name string, content []byte) {
+// Add adds a file with the given content to dir, creating dir and
+// any missing parents. An existing file at the same path is replaced.
+// The file has the default permission bits 0444, use AddFile to set them.
+func (fs *FS) Add(dir, name string, content []byte) error {
+	return fs.AddFile(pathpkg.Join("/", dir, name), content, AddFileOptions{})
+}
+
+// WriteFile writes content to the file at path, replacing it if it exists,
+// in which case its permission bits are kept.
+// Unlike Add, the parent directory must already exist.
+func (fs *FS) WriteFile(path string, content []byte) error {
+	return fs.writeFile(path, content, 0)
+}
+
+// writeFile is like WriteFile, but sets the permission bits of the file to mode
+// unless it's zero.
+func (fs *FS) writeFile(path string, content []byte, mode os.FileMode) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
 	
+	path = fs.resolveParent(path)
+	if path == "/" {
+		return &os.PathError{Op: "write", Path: path, Err: errIsDir}
+	}
+	if _, ok := fs.paths[path].(*DirInfo); ok {
+		return &os.PathError{Op: "write", Path: path, Err: errIsDir}
+	}
+	if err := fs.checkParent("write", path); err != nil {
+		return err
+	}
+	fs.version++
+	f := newCompressedFileInfo(pathpkg.Base(path), content)
+	if old, ok := fs.paths[path].(os.FileInfo); ok && !isSymlink(old) {
+		f.mode = old.Mode().Perm()
+	}
+	if mode != 0 {
+		f.mode = mode.Perm()
+	}
+	return fs.put(path, f)
+}
+
+// Mkdir creates a new directory at path. The parent directory must already exist.
+func (fs *FS) Mkdir(path string) error {
 	fs.lock.Lock()
 	defer func() {
 		fs.lock.Unlock()
 	}()
 	
-	if fs.paths == nil {
-		fs.paths = map[string]interface{}{}
+	fs.init()
+	
+	path = fs.resolveParent(path)
+	if _, ok := fs.paths[path]; ok {
+		return &os.PathError{Op: "mkdir", Path: path, Err: os.ErrExist}
 	}
+	if err := fs.checkParent("mkdir", path); err != nil {
+		return err
+	}
+	fs.version++
+	return fs.put(path, newDirInfo(path))
+}
+
+// MkdirAll creates a directory at path, along with any missing parents.
+// It does nothing if the directory already exists.
+func (fs *FS) MkdirAll(path string) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
 	
-	w := &bytes.Buffer{}
+	fs.init()
 	
-	gw := gzip.NewWriter(w)
-	_, _ = gw.Write(content)
-	_ = gw.Flush()
-	_ = gw.Close()
+	path = fs.resolve(path)
+	if _, ok := fs.paths[path].(*DirInfo); ok {
+		return nil
+	}
+	if err := fs.checkMkdirAll(path); err != nil {
+		return err
+	}
+	fs.version++
+	return fs.mkdirAll(path)
+}
+
+// RemoveAll removes path and any children it contains.
+// It returns nil if path doesn't exist. Removing "/" empties the filesystem.
+func (fs *FS) RemoveAll(path string) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
 	
-	path := filepath.Join(dir, name)
-	fs.paths[path] = &CompressedFileInfo{
-		name:              name,
-		modTime:           time.Now(),
-		uncompressedSize:  int64(len(content)),
-		compressedContent: w.Bytes(),
+	fs.init()
+	
+	path = fs.resolve(path)
+	if _, ok := fs.paths[path]; !ok {
+		return nil
+	}
+	fs.version++
+	fs.removeAll(path)
+	return nil
+}
+
+// Rename moves oldpath and any children it contains to newpath.
+// An existing file at newpath is replaced, an existing directory must be empty.
+func (fs *FS) Rename(oldpath, newpath string) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	oldpath = fs.resolve(oldpath)
+	newpath = fs.resolveParent(newpath)
+	if oldpath == "/" || newpath == "/" {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrInvalid}
+	}
+	src, ok := fs.paths[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrNotExist}
+	}
+	if oldpath == newpath {
+		return nil
+	}
+	if strings.HasPrefix(newpath, oldpath+"/") {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: os.ErrInvalid}
+	}
+	if err := fs.checkParent("rename", newpath); err != nil {
+		return err
+	}
+	if fs.collides(newpath) && fs.index[fs.key(newpath)] != oldpath {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrNameCollision}
+	}
+	dst, replace := fs.paths[newpath]
+	if replace {
+		_, srcDir := src.(*DirInfo)
+		d, dstDir := dst.(*DirInfo)
+		switch {
+		case dstDir && !srcDir:
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errIsDir}
+		case !dstDir && srcDir:
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: ErrNotDir}
+		case dstDir && len(d.entries) > 0:
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: errNotEmpty}
+		}
+	}
+	fs.version++
+	if replace {
+		fs.removeAll(newpath)
 	}
 	
-	parent := ""
-	for _, v := range filepath.SplitList(dir) {
-		parent += filepath.Join(parent, v)
-		if _, ok := fs.paths[parent]; ok {
-			continue
+	moved := map[string]interface{}{}
+	for k, v := range fs.paths {
+		if k == oldpath || strings.HasPrefix(k, oldpath+"/") {
+			moved[newpath+strings.TrimPrefix(k, oldpath)] = v
 		}
-		fs.paths[parent] = &DirInfo{
-			name:    parent,
-			modTime: time.Now(),
+	}
+	fs.removeAll(oldpath)
+	for k, v := range moved {
+		if k != newpath {
+			fs.set(k, v)
 		}
 	}
+	return fs.put(newpath, renamed(src, pathpkg.Base(newpath)))
+}
+
+// Replace replaces the subtree at dir with the contents of src in a single step,
+// so that readers see either the old or the new subtree, never a mix of both.
+// Missing parents of dir are created. src must not be used afterwards.
+func (fs *FS) Replace(dir string, src *FS) error {
+	src.lock.Lock()
+	defer func() {
+		src.lock.Unlock()
+	}()
+	
+	src.init()
+	
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
 	
-	fs.adjustEntries()
+	fs.init()
 	
-	return
+	dir = fs.resolve(dir)
+	if fs.key != nil {
+		seen := map[string]string{}
+		for k := range src.paths {
+			key := fs.key(k)
+			if other, ok := seen[key]; ok {
+				return &os.PathError{Op: "replace", Path: pathpkg.Join(dir, k), Err: fmt.Errorf("%w with %s", ErrNameCollision, pathpkg.Join(dir, other))}
+			}
+			seen[key] = k
+		}
+	}
+	if err := fs.checkMkdirAll(pathpkg.Dir(dir)); err != nil {
+		return err
+	}
+	fs.version++
+	if err := fs.mkdirAll(pathpkg.Dir(dir)); err != nil {
+		return err
+	}
+	if _, ok := fs.paths[dir]; ok {
+		fs.removeAll(dir)
+	}
+	for k, v := range src.paths {
+		if k != "/" {
+			fs.set(pathpkg.Join(dir, k), v)
+		}
+	}
+	return fs.put(dir, renamed(src.paths["/"], pathpkg.Base(dir)))
 }
 
-// TODO 处理递归目录的情况，现在支持一级目录
-func (fs *FS) adjustEntries() {
-	for _, v := range fs.paths {
-		switch dir := v.(type) {
-		case *DirInfo:
-			dir.entries = []os.FileInfo{}
-		}
+// Stat returns the os.FileInfo describing path.
+func (fs *FS) Stat(path string) (os.FileInfo, error) {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	path, err := fs.evalSymlinks(path, true)
+	if err != nil {
+		return nil, err
 	}
-	for k, v := range fs.paths {
-		switch v.(type) {
-		case *CompressedFileInfo:
-			pk := filepath.Join(k, "../")
-			pi := fs.paths[pk]
-			p, ok := pi.(*DirInfo)
-			if ok {
-				p.entries = append(p.entries, v.(os.FileInfo))
-			}
+	f, ok := fs.paths[path]
+	if !ok {
+		return nil, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+	}
+	return f.(os.FileInfo), nil
+}
+
+// Chmod changes the permission bits of the file or directory at path to those of mode.
+// A mode without permission bits restores the default, 0444 for files and 0755 for directories.
+func (fs *FS) Chmod(path string, mode os.FileMode) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	path, err := fs.evalSymlinks(path, true)
+	if err != nil {
+		return err
+	}
+	var changed interface{}
+	switch f := fs.paths[path].(type) {
+	case nil:
+		return &os.PathError{Op: "chmod", Path: path, Err: os.ErrNotExist}
+	case *CompressedFileInfo:
+		c := *f
+		c.mode = mode.Perm()
+		changed = &c
+	case *DirInfo:
+		changed = &DirInfo{name: f.name, modTime: f.modTime, mode: mode.Perm(), entries: f.entries}
+	case *lazyFileInfo:
+		c := f.renamed(f.name)
+		c.mode = mode.Perm()
+		if c.file != nil {
+			cf := *c.file
+			cf.mode = c.mode
+			c.file = &cf
 		}
+		changed = c
+	default:
+		return &os.PathError{Op: "chmod", Path: path, Err: os.ErrInvalid}
 	}
+	fs.version++
+	return fs.put(path, changed)
 }
 
-func (fs *FS) Open(path string) (http.File, error) {
+// insert stores the file definition f at path, creating missing parents.
+func (fs *FS) insert(path string, f interface{}) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
 	
+	fs.init()
+	
+	path = fs.resolveParent(path)
+	if err := fs.checkMkdirAll(pathpkg.Dir(path)); err != nil {
+		return err
+	}
+	fs.version++
+	if err := fs.mkdirAll(pathpkg.Dir(path)); err != nil {
+		return err
+	}
+	return fs.put(path, f)
+}
+
+// snapshot returns a consistent copy of the paths in fs, the versions
+// at which they last changed, and the current version of fs.
+func (fs *FS) snapshot() (map[string]interface{}, map[string]uint64, uint64) {
 	fs.lock.Lock()
 	defer func() {
 		fs.lock.Unlock()
 	}()
 	
+	fs.init()
+	
+	paths := make(map[string]interface{}, len(fs.paths))
+	versions := make(map[string]uint64, len(fs.versions))
+	for k, v := range fs.paths {
+		paths[k] = v
+		versions[k] = fs.versions[k]
+	}
+	return paths, versions, fs.version
+}
+
+// Version returns a number that increases every time the contents of fs change.
+func (fs *FS) Version() uint64 {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	return fs.version
+}
+
+var (
+	// ErrNotDir is returned when a path that must be a directory is not one.
+	ErrNotDir = errors.New("not a directory")
+	
+	// ErrNameCollision is returned when a name differs from the name of an existing
+	// file or directory, but matches it because names are matched case-insensitively
+	// or after Unicode normalization.
+	ErrNameCollision = errors.New("name collides with an existing name")
+	
+	errIsDir    = errors.New("is a directory")
+	errNotEmpty = errors.New("directory not empty")
+)
+
+// init makes the zero value of FS usable, fs.lock must be held.
+func (fs *FS) init() {
 	if fs.paths == nil {
 		fs.paths = map[string]interface{}{}
 	}
-	
+	if fs.versions == nil {
+		fs.versions = map[string]uint64{}
+	}
+	if fs.links == nil {
+		fs.links = map[*blob]int{}
+	}
+	if fs.key != nil && fs.index == nil {
+		fs.index = map[string]string{}
+		for k := range fs.paths {
+			fs.index[fs.key(k)] = k
+		}
+	}
+	if _, ok := fs.paths["/"]; !ok {
+		fs.set("/", newDirInfo("/"))
+	}
+}
+
+// resolve cleans path, and replaces the parts of it that match
+// existing files or directories with their names.
+func (fs *FS) resolve(path string) string {
 	path = pathpkg.Clean("/" + path)
+	if fs.key == nil {
+		return path
+	}
+	if p, ok := fs.index[fs.key(path)]; ok {
+		return p
+	}
+	if path == "/" {
+		return path
+	}
+	return pathpkg.Join(fs.resolve(pathpkg.Dir(path)), pathpkg.Base(path))
+}
+
+// resolveParent is like resolve, but keeps the last element of path as is.
+func (fs *FS) resolveParent(path string) string {
+	path = pathpkg.Clean("/" + path)
+	if path == "/" {
+		return path
+	}
+	return pathpkg.Join(fs.resolve(pathpkg.Dir(path)), pathpkg.Base(path))
+}
+
+// collides reports whether path matches a different existing path.
+func (fs *FS) collides(path string) bool {
+	if fs.key == nil {
+		return false
+	}
+	p, ok := fs.index[fs.key(path)]
+	return ok && p != path
+}
+
+// set stores f at path, without linking it into its parent directory.
+func (fs *FS) set(path string, f interface{}) {
+	if b := blobOf(f); b != nil {
+		fs.links[b]++
+	}
+	fs.unref(fs.paths[path])
+	fs.paths[path] = f
+	fs.versions[path] = fs.version
+	if fs.key != nil {
+		fs.index[fs.key(path)] = path
+	}
+}
+
+// checkParent verifies that the parent of path exists and is a directory.
+func (fs *FS) checkParent(op, path string) error {
+	switch fs.paths[pathpkg.Dir(path)].(type) {
+	case *DirInfo:
+		return nil
+	case nil:
+		return &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	default:
+		return &os.PathError{Op: op, Path: path, Err: ErrNotDir}
+	}
+}
+
+// checkMkdirAll verifies that mkdirAll can create the directory at path,
+// so that callers don't change the version of fs when it can't.
+func (fs *FS) checkMkdirAll(path string) error {
+	for ; ; path = pathpkg.Dir(path) {
+		switch fs.paths[path].(type) {
+		case *DirInfo:
+			return nil
+		case nil:
+			if fs.collides(path) {
+				return &os.PathError{Op: "mkdir", Path: path, Err: ErrNameCollision}
+			}
+		default:
+			return &os.PathError{Op: "mkdir", Path: path, Err: ErrNotDir}
+		}
+	}
+}
+
+// mkdirAll creates the directory at path and any missing parents.
+func (fs *FS) mkdirAll(path string) error {
+	switch fs.paths[path].(type) {
+	case *DirInfo:
+		return nil
+	case nil:
+	default:
+		return &os.PathError{Op: "mkdir", Path: path, Err: ErrNotDir}
+	}
+	if err := fs.mkdirAll(pathpkg.Dir(path)); err != nil {
+		return err
+	}
+	return fs.put(path, newDirInfo(path))
+}
+
+// put stores f at path and links it into the entries of its parent directory,
+// which must exist.
+func (fs *FS) put(path string, f interface{}) error {
+	if path == "/" {
+		fs.set(path, f)
+		return nil
+	}
+	parent, ok := fs.paths[pathpkg.Dir(path)].(*DirInfo)
+	if !ok {
+		return &os.PathError{Op: "open", Path: path, Err: ErrNotDir}
+	}
+	if fs.collides(path) {
+		return &os.PathError{Op: "open", Path: path, Err: ErrNameCollision}
+	}
+	fs.set(path, f)
+	parent.setEntry(f.(os.FileInfo))
+	return nil
+}
+
+// removeAll removes path and its children, and unlinks it from its parent directory.
+func (fs *FS) removeAll(path string) {
+	for k := range fs.paths {
+		if k == path || strings.HasPrefix(k, path+"/") || path == "/" {
+			fs.unref(fs.paths[k])
+			delete(fs.paths, k)
+			delete(fs.versions, k)
+			if fs.key != nil {
+				delete(fs.index, fs.key(k))
+			}
+		}
+	}
+	if path == "/" {
+		fs.set("/", newDirInfo("/"))
+		return
+	}
+	if parent, ok := fs.paths[pathpkg.Dir(path)].(*DirInfo); ok {
+		parent.removeEntry(pathpkg.Base(path))
+	}
+}
+
+// renamed returns a copy of f with a different name.
+func renamed(f interface{}, name string) interface{} {
+	switch f := f.(type) {
+	case *CompressedFileInfo:
+		c := *f
+		c.name = name
+		return &c
+	case *DirInfo:
+		return &DirInfo{name: name, modTime: f.modTime, mode: f.mode, entries: f.entries}
+	case *lazyFileInfo:
+		return f.renamed(name)
+	case *symlinkInfo:
+		return &symlinkInfo{name: name, modTime: f.modTime, target: f.target}
+	default:
+		// This should never happen because we store only the above types.
+		panic(fmt.Sprintf("unexpected type %T", f))
+	}
+}
+
+func (fs *FS) Open(path string) (http.File, error) {
+	
+	fs.lock.Lock()
+	fs.init()
+	path, err := fs.evalSymlinks(path, true)
 	f, ok := fs.paths[path]
+	var entries []os.FileInfo
+	if d, isDir := f.(*DirInfo); isDir {
+		// Entries are replaced under the lock, take the snapshot while holding it.
+		entries = d.entries
+	}
+	fs.lock.Unlock()
+	if err != nil {
+		return nil, err
+	}
 	if !ok {
 		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
 	}
 	
 	switch f := f.(type) {
-	case *CompressedFileInfo:
-		gr, err := gzip.NewReader(bytes.NewReader(f.compressedContent))
+	case *CompressedFileInfo, *lazyFileInfo:
+		// Lazy files are produced without holding the lock.
+		cf, err := compressed(f)
+		if err != nil {
+			return nil, &os.PathError{Op: "open", Path: path, Err: err}
+		}
+		gr, err := gzip.NewReader(bytes.NewReader(cf.compressedContent))
 		if err != nil {
 			// This should never happen because we generate the gzip bytes such that they are always valid.
 			panic("unexpected error reading own gzip compressed bytes: " + err.Error())
 		}
 		return &CompressedFile{
-			CompressedFileInfo: f,
+			CompressedFileInfo: cf,
 			gr:                 gr,
 		}, nil
 	case *DirInfo:
 		return &Dir{
 			DirInfo: f,
+			entries: entries,
 		}, nil
 	default:
 		// This should never happen because we generate only the above types.
@@ -141,12 +617,48 @@ func (fs *FS) Open(path string) (http.File, error) {
 	}
 }
 
+// compressed returns the compressed file definition of the stored file f,
+// producing its content if needed.
+func compressed(f interface{}) (*CompressedFileInfo, error) {
+	switch f := f.(type) {
+	case *CompressedFileInfo:
+		return f, nil
+	case *lazyFileInfo:
+		return f.produce()
+	default:
+		return nil, errIsDir
+	}
+}
+
+// newCompressedFileInfo gzip compresses content and returns its file definition.
+func newCompressedFileInfo(name string, content []byte) *CompressedFileInfo {
+	w := &bytes.Buffer{}
+	
+	gw := gzip.NewWriter(w)
+	_, _ = gw.Write(content)
+	_ = gw.Flush()
+	_ = gw.Close()
+	
+	return &CompressedFileInfo{
+		name:              name,
+		modTime:           time.Now(),
+		uncompressedSize:  int64(len(content)),
+		compressedContent: w.Bytes(),
+		meta:              &FileMeta{CompressedSize: int64(w.Len()), Encoding: "gzip"},
+		detect:            &metaDetection{name: name},
+	}
+}
+
 // CompressedFileInfo is a static definition of a gzip compressed file.
 type CompressedFileInfo struct {
 	name              string
 	modTime           time.Time
+	mode              os.FileMode // Permission bits, zero for the default 0444.
 	compressedContent []byte
 	uncompressedSize  int64
+	meta              *FileMeta      // Shared by renamed copies, nil if unknown.
+	detect            *metaDetection // Shared by renamed copies, nil if meta is complete.
+	blob              *blob          // Shared by the paths created with Link, nil if not linked.
 }
 
 func (f *CompressedFileInfo) Readdir(count int) ([]os.FileInfo, error) {
@@ -158,12 +670,27 @@ func (f *CompressedFileInfo) GzipBytes() []byte {
 	return f.compressedContent
 }
 
-func (f *CompressedFileInfo) Name() string       { return f.name }
-func (f *CompressedFileInfo) Size() int64        { return f.uncompressedSize }
-func (f *CompressedFileInfo) Mode() os.FileMode  { return 0444 }
+// ContentType returns the MIME type of the file, or "" if it's unknown.
+func (f *CompressedFileInfo) ContentType() string {
+	return f.contentType()
+}
+
+func (f *CompressedFileInfo) Name() string { return f.name }
+func (f *CompressedFileInfo) Size() int64  { return f.uncompressedSize }
+func (f *CompressedFileInfo) Mode() os.FileMode {
+	if f.mode == 0 {
+		return 0444
+	}
+	return f.mode
+}
 func (f *CompressedFileInfo) ModTime() time.Time { return f.modTime }
 func (f *CompressedFileInfo) IsDir() bool        { return false }
-func (f *CompressedFileInfo) Sys() interface{}   { return nil }
+func (f *CompressedFileInfo) Sys() interface{} {
+	if m := f.metadata(); m != nil {
+		return m
+	}
+	return nil
+}
 
 // CompressedFile is an opened compressedFile instance.
 type CompressedFile struct {
@@ -216,6 +743,7 @@ func (f *CompressedFile) Close() error {
 type DirInfo struct {
 	name    string
 	modTime time.Time
+	mode    os.FileMode // Permission bits, zero for the default 0755.
 	entries []os.FileInfo
 }
 
@@ -225,17 +753,56 @@ func (d *DirInfo) Read([]byte) (int, error) {
 func (d *DirInfo) Close() error               { return nil }
 func (d *DirInfo) Stat() (os.FileInfo, error) { return d, nil }
 
-func (d *DirInfo) Name() string       { return d.name }
-func (d *DirInfo) Size() int64        { return 0 }
-func (d *DirInfo) Mode() os.FileMode  { return 0755 | os.ModeDir }
+func (d *DirInfo) Name() string { return d.name }
+func (d *DirInfo) Size() int64  { return 0 }
+func (d *DirInfo) Mode() os.FileMode {
+	if d.mode == 0 {
+		return 0755 | os.ModeDir
+	}
+	return d.mode | os.ModeDir
+}
 func (d *DirInfo) ModTime() time.Time { return d.modTime }
 func (d *DirInfo) IsDir() bool        { return true }
 func (d *DirInfo) Sys() interface{}   { return nil }
 
+// newDirInfo returns the definition of an empty directory at path.
+func newDirInfo(path string) *DirInfo {
+	return &DirInfo{
+		name:    pathpkg.Base(path),
+		modTime: time.Now(),
+	}
+}
+
+// setEntry adds fi to the directory entries, replacing an entry with the same name.
+// Entries are kept sorted by name, and copied on write so that opened directories
+// keep a consistent view.
+func (d *DirInfo) setEntry(fi os.FileInfo) {
+	i := sort.Search(len(d.entries), func(i int) bool { return d.entries[i].Name() >= fi.Name() })
+	entries := make([]os.FileInfo, 0, len(d.entries)+1)
+	entries = append(entries, d.entries[:i]...)
+	entries = append(entries, fi)
+	if i < len(d.entries) && d.entries[i].Name() == fi.Name() {
+		i++
+	}
+	d.entries = append(entries, d.entries[i:]...)
+}
+
+// removeEntry removes the entry with the given name from the directory entries.
+func (d *DirInfo) removeEntry(name string) {
+	i := sort.Search(len(d.entries), func(i int) bool { return d.entries[i].Name() >= name })
+	if i == len(d.entries) || d.entries[i].Name() != name {
+		return
+	}
+	entries := make([]os.FileInfo, 0, len(d.entries)-1)
+	entries = append(entries, d.entries[:i]...)
+	d.entries = append(entries, d.entries[i+1:]...)
+}
+
 // Dir is an opened dir instance.
 type Dir struct {
 	*DirInfo
-	pos int // Position within entries for Seek and Readdir.
+	entries []os.FileInfo // Snapshot of DirInfo entries taken at Open.
+	pos     int           // Position within entries for Seek and Readdir.
 }
 
 func (d *Dir) Seek(offset int64, whence int) (int64, error) {
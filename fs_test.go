@@ -1,6 +1,10 @@
 package vfs
 
 import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -28,3 +32,174 @@ func TestFS(t *testing.T) {
 		}
 	}
 }
+
+func TestFSMutations(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/a/b", "1.txt", []byte("1"))
+	
+	if err := fs.Mkdir("/a/c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("/a/c"); !os.IsExist(err) {
+		t.Errorf("Mkdir existing: got %v, want exist error", err)
+	}
+	if err := fs.Mkdir("/x/y"); !os.IsNotExist(err) {
+		t.Errorf("Mkdir without parent: got %v, want not exist error", err)
+	}
+	if err := fs.WriteFile("/a/c/2.txt", []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readDirNamesT(t, fs, "/a"), "b,c"; got != want {
+		t.Errorf("/a entries: got %q, want %q", got, want)
+	}
+	
+	if err := fs.Rename("/a/c", "/d"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readDirNamesT(t, fs, "/"), "a,d"; got != want {
+		t.Errorf("/ entries: got %q, want %q", got, want)
+	}
+	if fi, err := fs.Stat("/d/2.txt"); err != nil || fi.Name() != "2.txt" {
+		t.Errorf("Stat /d/2.txt: got %v, %v", fi, err)
+	}
+	if _, err := fs.Stat("/a/c/2.txt"); !os.IsNotExist(err) {
+		t.Errorf("Stat old path: got %v, want not exist error", err)
+	}
+	if err := fs.Rename("/a", "/a/b/z"); err == nil {
+		t.Error("Rename into own subtree: got nil error")
+	}
+	
+	if err := fs.RemoveAll("/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat("/a/b/1.txt"); !os.IsNotExist(err) {
+		t.Errorf("Stat removed file: got %v, want not exist error", err)
+	}
+	if got, want := readDirNamesT(t, fs, "/"), "d"; got != want {
+		t.Errorf("/ entries: got %q, want %q", got, want)
+	}
+}
+
+func TestFSModes(t *testing.T) {
+	fs := NewFS()
+	if err := fs.AddFile("/bin/run.sh", []byte("#!/bin/sh"), AddFileOptions{Mode: 0755}); err != nil {
+		t.Fatal(err)
+	}
+	fs.Add("/", "readme.txt", []byte("Hello."))
+	
+	for _, test := range []struct {
+		path string
+		want os.FileMode
+	}{
+		{"/bin/run.sh", 0755},
+		{"/readme.txt", 0444},
+		{"/bin", 0755 | os.ModeDir},
+	} {
+		if fi, err := fs.Stat(test.path); err != nil || fi.Mode() != test.want {
+			t.Errorf("Stat %s: got %v, %v, want mode %v", test.path, fi, err, test.want)
+		}
+	}
+	
+	if err := fs.Chmod("/readme.txt", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("/readme.txt", []byte("Changed.")); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := fs.Stat("/readme.txt"); err != nil || fi.Mode() != 0600 {
+		t.Errorf("Stat after Chmod and WriteFile: got %v, %v, want mode 0600", fi, err)
+	}
+	if err := fs.Chmod("/bin", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := fs.Stat("/bin"); err != nil || fi.Mode() != 0700|os.ModeDir {
+		t.Errorf("Stat directory after Chmod: got %v, %v, want mode %v", fi, err, 0700|os.ModeDir)
+	}
+	if err := fs.Chmod("/missing", 0600); !os.IsNotExist(err) {
+		t.Errorf("Chmod missing file: got %v, want not exist error", err)
+	}
+}
+
+func TestAddErrors(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/", "file.txt", []byte("file"))
+	fs.Add("/dir", "child.txt", nil)
+	fs.Add("/other", "child.txt", nil)
+	version := fs.Version()
+	if err := fs.Add("/file.txt", "child.txt", nil); !errors.Is(err, ErrNotDir) {
+		t.Errorf("Add below a file: got %v, want ErrNotDir", err)
+	}
+	if err := fs.MkdirAll("/file.txt/dir"); !errors.Is(err, ErrNotDir) {
+		t.Errorf("MkdirAll below a file: got %v, want ErrNotDir", err)
+	}
+	if err := fs.Add("/", "", nil); err == nil {
+		t.Error("Add over the root: got nil error")
+	}
+	if err := fs.Rename("/file.txt", "/dir"); err == nil {
+		t.Error("Rename a file over a directory: got nil error")
+	}
+	if err := fs.Rename("/dir", "/file.txt"); err == nil {
+		t.Error("Rename a directory over a file: got nil error")
+	}
+	if err := fs.Rename("/other", "/dir"); err == nil {
+		t.Error("Rename over a non empty directory: got nil error")
+	}
+	if err := fs.Link("/file.txt", "/dir"); err == nil {
+		t.Error("Link over a directory: got nil error")
+	}
+	if fs.Version() != version {
+		t.Errorf("failed calls changed the version from %d to %d", version, fs.Version())
+	}
+}
+
+func TestLinkCollision(t *testing.T) {
+	fs := NewFSWithOptions(FSOptions{CaseInsensitive: true})
+	fs.Add("/", "a.txt", []byte("a"))
+	fs.Add("/", "B.txt", []byte("b"))
+	version := fs.Version()
+	if err := fs.Link("/a.txt", "/b.txt"); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("Link to a colliding name: got %v, want ErrNameCollision", err)
+	}
+	if fs.Version() != version {
+		t.Errorf("failed Link changed the version from %d to %d", version, fs.Version())
+	}
+}
+
+func TestFSConcurrentOpenAdd(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/d", "0.txt", []byte("0"))
+	
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 100; i++ {
+			if err := fs.Add("/d", strconv.Itoa(i)+".txt", []byte("i")); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		f, err := fs.Open("/d")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Readdir(0); err != nil {
+			t.Fatal(err)
+		}
+		_ = f.Close()
+	}
+	<-done
+	if fis, err := readDirNames(fs, "/d"); err != nil || len(fis) != 101 {
+		t.Errorf("/d: got %d entries, %v, want 101", len(fis), err)
+	}
+}
+
+func readDirNamesT(t *testing.T, fs http.FileSystem, dir string) string {
+	t.Helper()
+	names, err := readDirNames(fs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(names, ",")
+}
@@ -3,7 +3,10 @@ package vfs
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -11,6 +14,8 @@ import (
 	pathpkg "path"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 	
@@ -21,34 +26,122 @@ import (
 // write the output to a file specified in opt.
 func Generate(input http.FileSystem, opt Options) error {
 	opt.fillMissing()
+	err := opt.fillReproducible()
+	if err != nil {
+		return err
+	}
+	if err := opt.Compression.validate(); err != nil {
+		return err
+	}
 	
-	// Use an in-memory buffer to generate the entire output.
-	buf := new(bytes.Buffer)
-	
-	err := t.ExecuteTemplate(buf, "Header", opt)
+	skipped := map[string]bool{}
+	input, err = filterInput(input, &opt, skipped)
 	if err != nil {
 		return err
 	}
 	
+	// Use in-memory buffers to generate the entire output.
+	// The header depends on the files found, so it's generated last.
+	body := new(bytes.Buffer)
+	
 	var toc toc
-	err = findAndWriteFiles(buf, input, &toc)
+	err = findAndWriteFiles(body, input, &opt, &toc)
+	if err != nil {
+		return err
+	}
+	
+	err = t.ExecuteTemplate(body, "DirEntries", toc.dirs)
 	if err != nil {
 		return err
 	}
 	
-	err = t.ExecuteTemplate(buf, "DirEntries", toc.dirs)
+	err = t.ExecuteTemplate(body, "Trailer", toc)
 	if err != nil {
 		return err
 	}
 	
-	err = t.ExecuteTemplate(buf, "Trailer", toc)
+	buf := new(bytes.Buffer)
+	err = t.ExecuteTemplate(buf, "Header", header{Options: &opt, HasSymlink: toc.HasSymlink})
 	if err != nil {
 		return err
 	}
+	_, _ = body.WriteTo(buf)
 	
 	// Write output file (all at once).
 	err = ioutil.WriteFile(opt.Filename, buf.Bytes(), 0644)
-	return err
+	if err != nil {
+		return err
+	}
+	
+	if opt.Skipped != nil {
+		paths := make([]string, 0, len(skipped))
+		for path := range skipped {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		opt.Skipped(paths)
+	}
+	return nil
+}
+
+// filterInput returns input without the files and directories left out by the Include
+// and Exclude options and the .vfsignore file, whose paths, other than the one of
+// the .vfsignore file itself, are added to skipped.
+func filterInput(input http.FileSystem, opt *Options, skipped map[string]bool) (http.FileSystem, error) {
+	exclude := opt.Exclude
+	b, err := vfsutil.ReadFile(input, "/"+ignoreFile)
+	switch {
+	case err == nil:
+		exclude = append(append(exclude[:len(exclude):len(exclude)], strings.Split(string(b), "\n")...), "/"+ignoreFile)
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	if len(exclude) == 0 && len(opt.Include) == 0 {
+		return input, nil
+	}
+	
+	m, err := compileMatcher(exclude)
+	if err != nil {
+		return nil, err
+	}
+	rules := FilterRules{Exclude: exclude, PruneEmptyDirs: len(opt.Include) > 0}
+	if len(opt.Include) > 0 {
+		include, err := compileMatcher(opt.Include)
+		if err != nil {
+			return nil, err
+		}
+		rules.Keep = func(path string, fi os.FileInfo) bool {
+			for p := path; p != "/"; p = pathpkg.Dir(p) {
+				if include.excluded(p, p != path) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	report := func(path string) {
+		if path != "/"+ignoreFile {
+			skipped[path] = true
+		}
+	}
+	filtered := http.FileSystem(&filterFS{fs: input, rules: rules, m: m, skipped: report})
+	if rl, ok := input.(Readlinker); ok {
+		// Symbolic links that aren't left out are read from the input.
+		filtered = struct {
+			http.FileSystem
+			Readlinker
+		}{filtered, rl}
+	}
+	return filtered, nil
+}
+
+// ignoreFile is the name of the file at the root of the input filesystem
+// listing gitignore style patterns of files and directories left out by Generate.
+const ignoreFile = ".vfsignore"
+
+type header struct {
+	*Options
+	HasSymlink bool
 }
 
 type toc struct {
@@ -56,6 +149,7 @@ type toc struct {
 	
 	HasCompressedFile bool // There's at least one compressedFile.
 	HasFile           bool // There's at least one uncompressed file.
+	HasSymlink        bool // There's at least one symbolic link.
 }
 
 // FileInfo is a definition of a file.
@@ -63,7 +157,17 @@ type FileInfo struct {
 	Path             string
 	Name             string
 	ModTime          time.Time
+	Mode             os.FileMode // Permission bits.
 	UncompressedSize int64
+	Meta             *FileMeta
+}
+
+// linkInfo is a definition of a symbolic link.
+type linkInfo struct {
+	Path    string
+	Name    string
+	ModTime time.Time
+	Target  string
 }
 
 // dirInfo is a definition of a directory.
@@ -71,54 +175,109 @@ type dirInfo struct {
 	Path    string
 	Name    string
 	ModTime time.Time
+	Mode    os.FileMode // Permission bits.
 	Entries []string
 }
 
 // findAndWriteFiles recursively finds all the file paths in the given directory tree.
 // They are added to the given map as keys. Values will be safe function names
 // for each file, which will be used when generating the output code.
-func findAndWriteFiles(buf *bytes.Buffer, fs http.FileSystem, toc *toc) error {
+func findAndWriteFiles(buf *bytes.Buffer, fs http.FileSystem, opt *Options, toc *toc) error {
+	contentTypes, err := compileContentTypeRules(opt.ContentTypes)
+	if err != nil {
+		return err
+	}
+	
+	// The definitions are generated in walk order, files are compressed concurrently.
+	var (
+		parts []*generatedPart
+		sem   = make(chan struct{}, opt.concurrency())
+		wg    sync.WaitGroup
+	)
+	newPart := func() *generatedPart {
+		p := &generatedPart{}
+		parts = append(parts, p)
+		return p
+	}
+	
 	walkFn := func(path string, fi os.FileInfo, r io.ReadSeeker, err error) error {
 		if err != nil {
 			// Consider all errors reading the input filesystem as fatal.
 			return err
 		}
 		
-		switch fi.IsDir() {
-		case false:
+		modTime, err := opt.modTime(path, fi)
+		if err != nil {
+			return err
+		}
+		
+		switch {
+		case fi.Mode()&os.ModeSymlink != 0:
+			rl, ok := fs.(Readlinker)
+			if !ok {
+				return fmt.Errorf("can't preserve symbolic link %s: input filesystem doesn't implement Readlinker", path)
+			}
+			target, err := rl.Readlink(path)
+			if err != nil {
+				return err
+			}
+			toc.HasSymlink = true
+			
+			// Write Symlink.
+			err = t.ExecuteTemplate(&newPart().buf, "Symlink", &linkInfo{
+				Path:    path,
+				Name:    pathpkg.Base(path),
+				ModTime: modTime,
+				Target:  target,
+			})
+			if err != nil {
+				return err
+			}
+		case !fi.IsDir():
 			file := &FileInfo{
 				Path:             path,
 				Name:             pathpkg.Base(path),
-				ModTime:          fi.ModTime().UTC(),
+				ModTime:          modTime,
+				Mode:             opt.mode(path, fi.Mode()),
 				UncompressedSize: fi.Size(),
+				Meta:             &FileMeta{Source: path},
+			}
+			if ctype, ok := contentTypes.match(path); ok {
+				file.Meta.ContentType = ctype
+			} else if file.Meta.ContentType, err = sniffContentType(path, r); err != nil {
+				return err
+			}
+			if opt.MetaValues != nil {
+				file.Meta.Values = opt.MetaValues(path)
 			}
 			
-			marker := buf.Len()
+			compress, err := opt.Compression.compress(path, fi, r)
+			if err != nil {
+				return err
+			}
 			
-			// Write CompressedFileInfo.
-			err = writeCompressedFileInfo(buf, file, r)
-			switch err {
-			default:
+			// Read the content and write the file in the background, keeping at most
+			// opt.Concurrency files in memory.
+			sem <- struct{}{}
+			content, err := ioutil.ReadAll(r)
+			if err != nil {
+				<-sem
 				return err
-			case nil:
-				toc.HasCompressedFile = true
-			// If compressed file is not smaller than original, revert and write original file.
-			case errCompressedNotSmaller:
-				_, err = r.Seek(0, io.SeekStart)
-				if err != nil {
-					return err
-				}
-				
-				buf.Truncate(marker)
-				
-				// Write FileInfo.
-				err = writeFileInfo(buf, file, r)
-				if err != nil {
-					return err
-				}
-				toc.HasFile = true
 			}
-		case true:
+			part := newPart()
+			part.file = true
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				if testHookWriteFile != nil {
+					testHookWriteFile()
+				}
+				part.compressed, part.err = writeFile(&part.buf, file, content, compress, &opt.Compression)
+			}()
+		default:
 			entries, err := readDirPaths(fs, path)
 			if err != nil {
 				return err
@@ -127,14 +286,15 @@ func findAndWriteFiles(buf *bytes.Buffer, fs http.FileSystem, toc *toc) error {
 			dir := &dirInfo{
 				Path:    path,
 				Name:    pathpkg.Base(path),
-				ModTime: fi.ModTime().UTC(),
+				ModTime: modTime,
+				Mode:    opt.mode(path, fi.Mode()),
 				Entries: entries,
 			}
 			
 			toc.dirs = append(toc.dirs, dir)
 			
 			// Write DirInfo.
-			err = t.ExecuteTemplate(buf, "DirInfo", dir)
+			err = t.ExecuteTemplate(&newPart().buf, "DirInfo", dir)
 			if err != nil {
 				return err
 			}
@@ -143,10 +303,121 @@ func findAndWriteFiles(buf *bytes.Buffer, fs http.FileSystem, toc *toc) error {
 		return nil
 	}
 	
-	err := vfsutil.WalkFiles(fs, "/", walkFn)
-	return err
+	root, err := stat(fs, "/")
+	if err != nil {
+		return err
+	}
+	err = walkInput(fs, "/", root, nil, opt.Symlinks, walkFn)
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	
+	for _, p := range parts {
+		if p.err != nil {
+			return p.err
+		}
+		switch {
+		case p.file && p.compressed:
+			toc.HasCompressedFile = true
+		case p.file:
+			toc.HasFile = true
+		}
+		_, _ = p.buf.WriteTo(buf)
+	}
+	return nil
+}
+
+// testHookWriteFile, if not nil, is called by the goroutines writing the
+// definitions of files before they start.
+var testHookWriteFile func()
+
+// generatedPart is the generated definition of a file, directory or symbolic link.
+type generatedPart struct {
+	buf        bytes.Buffer
+	file       bool  // Whether it's a file definition.
+	compressed bool  // Whether the file is compressed, set once written.
+	err        error // Error writing the file.
+}
+
+// writeFile writes the definition of file, with the given content, to w. If compress is set,
+// the file is compressed unless it doesn't save enough space according to c.
+// It reports whether the file was compressed.
+func writeFile(w *bytes.Buffer, file *FileInfo, content []byte, compress bool, c *CompressionOptions) (compressed bool, err error) {
+	// Write CompressedFileInfo.
+	err = errCompressedNotSmaller
+	if compress {
+		err = writeCompressedFileInfo(w, file, bytes.NewReader(content), c)
+	}
+	switch err {
+	default:
+		return false, err
+	case nil:
+		return true, nil
+	// If compressed file is not small enough, or wasn't compressed, revert and write original file.
+	case errCompressedNotSmaller:
+		w.Reset()
+		
+		// Write FileInfo.
+		return false, writeFileInfo(w, file, bytes.NewReader(content))
+	}
 }
 
+// walkInput walks the input filesystem like vfsutil.WalkFiles, handling symbolic links
+// according to policy. fi describes path, without following it if it's a symbolic link.
+// Preserved symbolic links are passed to walkFn with a nil reader.
+// ancestors are the directories containing path, used to detect symbolic link loops.
+func walkInput(fs http.FileSystem, path string, fi os.FileInfo, ancestors []os.FileInfo, policy SymlinkPolicy, walkFn vfsutil.WalkFilesFunc) error {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		switch policy {
+		case PreserveSymlinks:
+			return walkFn(path, fi, nil, nil)
+		case RejectSymlinks:
+			return walkFn(path, nil, nil, &os.PathError{Op: "generate", Path: path, Err: errSymlinkRejected})
+		}
+	}
+	
+	f, err := fs.Open(path)
+	if err != nil {
+		return walkFn(path, nil, nil, err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	
+	fi, err = f.Stat()
+	if err != nil {
+		return walkFn(path, nil, nil, err)
+	}
+	if !fi.IsDir() {
+		return walkFn(path, fi, f, nil)
+	}
+	
+	for _, a := range ancestors {
+		if sameDir(a, fi) {
+			return walkFn(path, nil, nil, &os.PathError{Op: "generate", Path: path, Err: ErrSymlinkLoop})
+		}
+	}
+	ancestors = append(ancestors[:len(ancestors):len(ancestors)], fi)
+	
+	entries, err := f.Readdir(0)
+	if err != nil {
+		return walkFn(path, nil, nil, err)
+	}
+	if err := walkFn(path, fi, nil, nil); err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		if err := walkInput(fs, pathpkg.Join(path, e.Name()), e, ancestors, policy, walkFn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errSymlinkRejected = errors.New("symbolic links are rejected")
+
 // readDirPaths reads the directory named by dirname and returns
 // a sorted list of directory paths.
 func readDirPaths(fs http.FileSystem, dirname string) ([]string, error) {
@@ -163,15 +434,21 @@ func readDirPaths(fs http.FileSystem, dirname string) ([]string, error) {
 }
 
 // writeCompressedFileInfo writes CompressedFileInfo.
-// It returns errCompressedNotSmaller if compressed file is not smaller than original.
-func writeCompressedFileInfo(w io.Writer, file *FileInfo, r io.Reader) error {
+// It returns errCompressedNotSmaller if compressed file doesn't save enough space according to c.
+func writeCompressedFileInfo(w io.Writer, file *FileInfo, r io.Reader, c *CompressionOptions) error {
 	err := t.ExecuteTemplate(w, "CompressedFileInfo-Before", file)
 	if err != nil {
 		return err
 	}
 	sw := &stringWriter{Writer: w}
-	gw, _ := gzip.NewWriterLevel(sw, gzip.BestCompression)
-	_, err = io.Copy(gw, r)
+	gw, err := gzip.NewWriterLevel(sw, c.level())
+	if err != nil {
+		return err
+	}
+	// The optional header fields, such as the mod time, are left unset,
+	// so the output only depends on the content.
+	h := sha256.New()
+	_, err = io.Copy(gw, io.TeeReader(r, h))
 	if err != nil {
 		return err
 	}
@@ -179,14 +456,16 @@ func writeCompressedFileInfo(w io.Writer, file *FileInfo, r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	if sw.N >= file.UncompressedSize {
+	if !c.worth(file.UncompressedSize, sw.N) {
 		return errCompressedNotSmaller
 	}
+	file.Meta.Hash = hex.EncodeToString(h.Sum(nil))
+	file.Meta.CompressedSize, file.Meta.Encoding = sw.N, "gzip"
 	err = t.ExecuteTemplate(w, "CompressedFileInfo-After", file)
 	return err
 }
 
-var errCompressedNotSmaller = errors.New("compressed file is not smaller than original")
+var errCompressedNotSmaller = errors.New("compressed file is not small enough")
 
 // Write FileInfo.
 func writeFileInfo(w io.Writer, file *FileInfo, r io.Reader) error {
@@ -195,16 +474,56 @@ func writeFileInfo(w io.Writer, file *FileInfo, r io.Reader) error {
 		return err
 	}
 	sw := &stringWriter{Writer: w}
-	_, err = io.Copy(sw, r)
+	h := sha256.New()
+	_, err = io.Copy(sw, io.TeeReader(r, h))
 	if err != nil {
 		return err
 	}
+	file.Meta.Hash = hex.EncodeToString(h.Sum(nil))
 	err = t.ExecuteTemplate(w, "FileInfo-After", file)
 	return err
 }
 
+// metaLiteral returns the Go expression of a pointer to m in the generated code,
+// leaving out zero fields.
+func metaLiteral(m *FileMeta) string {
+	var fields []string
+	add := func(name, value string) {
+		fields = append(fields, name+": "+value)
+	}
+	if m.ContentType != "" {
+		add("ContentType", strconv.Quote(m.ContentType))
+	}
+	if m.Hash != "" {
+		add("Hash", strconv.Quote(m.Hash))
+	}
+	if m.CompressedSize != 0 {
+		add("CompressedSize", strconv.FormatInt(m.CompressedSize, 10))
+	}
+	if m.Encoding != "" {
+		add("Encoding", strconv.Quote(m.Encoding))
+	}
+	if m.Source != "" {
+		add("Source", strconv.Quote(m.Source))
+	}
+	if len(m.Values) > 0 {
+		keys := make([]string, 0, len(m.Values))
+		for k := range m.Values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			values[i] = strconv.Quote(k) + ": " + strconv.Quote(m.Values[k])
+		}
+		add("Values", "map[string]string{"+strings.Join(values, ", ")+"}")
+	}
+	return "&vfsgen۰FileMeta{" + strings.Join(fields, ", ") + "}"
+}
+
 var t = template.Must(template.New("").Funcs(template.FuncMap{
 	"quote": strconv.Quote,
+	"meta":  metaLiteral,
 	"comment": func(s string) (string, error) {
 		var buf bytes.Buffer
 		cw := &commentWriter{W: &buf}
@@ -229,7 +548,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	pathpkg "path"
+	pathpkg "path"{{if .HasSymlink}}
+	"strings"{{end}}
 	"time"
 )
 
@@ -243,9 +563,12 @@ var {{.VariableName}} = func() http.FileSystem {
 {{define "CompressedFileInfo-Before"}}		{{quote .Path}}: &vfsgen۰CompressedFileInfo{
 			name:             {{quote .Name}},
 			modTime:          {{template "Time" .ModTime}},
+			mode:             {{printf "%#o" .Mode}},
 			uncompressedSize: {{.UncompressedSize}},
 {{/* This blank line separating compressedContent is neccessary to prevent potential gofmt issues. See issue #19. */}}
 			compressedContent: []byte("{{end}}{{define "CompressedFileInfo-After"}}"),
+
+			meta: {{meta .Meta}},
 		},
 {{end}}
 
@@ -254,7 +577,19 @@ var {{.VariableName}} = func() http.FileSystem {
 {{define "FileInfo-Before"}}		{{quote .Path}}: &vfsgen۰FileInfo{
 			name:    {{quote .Name}},
 			modTime: {{template "Time" .ModTime}},
+			mode:    {{printf "%#o" .Mode}},
 			content: []byte("{{end}}{{define "FileInfo-After"}}"),
+
+			meta: {{meta .Meta}},
+		},
+{{end}}
+
+
+
+{{define "Symlink"}}		{{quote .Path}}: &vfsgen۰Symlink{
+			name:    {{quote .Name}},
+			modTime: {{template "Time" .ModTime}},
+			target:  {{quote .Target}},
 		},
 {{end}}
 
@@ -263,16 +598,17 @@ var {{.VariableName}} = func() http.FileSystem {
 {{define "DirInfo"}}		{{quote .Path}}: &vfsgen۰DirInfo{
 			name:    {{quote .Name}},
 			modTime: {{template "Time" .ModTime}},
+			mode:    {{printf "%#o" .Mode}},
 		},
 {{end}}
 
 
 
 {{define "DirEntries"}}	}
-{{range .}}{{if .Entries}}	fs[{{quote .Path}}].(*vfsgen۰DirInfo).entries = []os.FileInfo{{"{"}}{{range .Entries}}
-		fs[{{quote .}}].(os.FileInfo),{{end}}
-	}
-{{end}}{{end}}
+{{range .}}	fs[{{quote .Path}}].(*vfsgen۰DirInfo).entries = []os.FileInfo{{"{"}}{{range .Entries}}
+		fs[{{quote .}}].(os.FileInfo),{{end}}{{if .Entries}}
+	{{end}}}
+{{end}}
 	return fs
 }()
 {{end}}
@@ -283,7 +619,11 @@ var {{.VariableName}} = func() http.FileSystem {
 type vfsgen۰FS map[string]interface{}
 
 func (fs vfsgen۰FS) Open(path string) (http.File, error) {
-	path = pathpkg.Clean("/" + path)
+	path = pathpkg.Clean("/" + path){{if .HasSymlink}}
+	path, err := fs.evalSymlinks(path, true)
+	if err != nil {
+		return nil, err
+	}{{end}}
 	f, ok := fs[path]
 	if !ok {
 		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
@@ -314,13 +654,66 @@ func (fs vfsgen۰FS) Open(path string) (http.File, error) {
 		panic(fmt.Sprintf("unexpected type %T", f))
 	}
 }
-{{if .HasCompressedFile}}
+{{if .HasSymlink}}
+// Readlink returns the target of the symbolic link at name.
+func (fs vfsgen۰FS) Readlink(name string) (string, error) {
+	path, err := fs.evalSymlinks(pathpkg.Clean("/"+name), false)
+	if err != nil {
+		return "", err
+	}
+	l, ok := fs[path].(*vfsgen۰Symlink)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: os.ErrInvalid}
+	}
+	return l.target, nil
+}
+
+// evalSymlinks resolves path, following symbolic links in it.
+// The last element is only followed if followLast is set.
+func (fs vfsgen۰FS) evalSymlinks(path string, followLast bool) (string, error) {
+	resolved, rest := "/", strings.Split(path, "/")
+	for hops := 0; len(rest) > 0; {
+		p := pathpkg.Join(resolved, rest[0])
+		rest = rest[1:]
+		l, ok := fs[p].(*vfsgen۰Symlink)
+		if !ok || len(rest) == 0 && !followLast {
+			resolved = p
+			continue
+		}
+		if hops++; hops > 40 {
+			return "", &os.PathError{Op: "open", Path: path, Err: fmt.Errorf("too many levels of symbolic links")}
+		}
+		target := l.target
+		if !strings.HasPrefix(target, "/") {
+			target = pathpkg.Join(resolved, target)
+		}
+		resolved, rest = "/", append(strings.Split(pathpkg.Clean("/"+target), "/"), rest...)
+	}
+	return resolved, nil
+}
+
+// vfsgen۰Symlink is a static definition of a symbolic link.
+type vfsgen۰Symlink struct {
+	name    string
+	modTime time.Time
+	target  string
+}
+
+func (l *vfsgen۰Symlink) Name() string       { return l.name }
+func (l *vfsgen۰Symlink) Size() int64        { return int64(len(l.target)) }
+func (l *vfsgen۰Symlink) Mode() os.FileMode  { return 0777 | os.ModeSymlink }
+func (l *vfsgen۰Symlink) ModTime() time.Time { return l.modTime }
+func (l *vfsgen۰Symlink) IsDir() bool        { return false }
+func (l *vfsgen۰Symlink) Sys() interface{}   { return nil }
+{{end}}{{if .HasCompressedFile}}
 // vfsgen۰CompressedFileInfo is a static definition of a gzip compressed file.
 type vfsgen۰CompressedFileInfo struct {
 	name              string
 	modTime           time.Time
+	mode              os.FileMode
 	compressedContent []byte
 	uncompressedSize  int64
+	meta              *vfsgen۰FileMeta
 }
 
 func (f *vfsgen۰CompressedFileInfo) Readdir(count int) ([]os.FileInfo, error) {
@@ -332,12 +725,16 @@ func (f *vfsgen۰CompressedFileInfo) GzipBytes() []byte {
 	return f.compressedContent
 }
 
+func (f *vfsgen۰CompressedFileInfo) ContentType() string {
+	return f.meta.ContentType
+}
+
 func (f *vfsgen۰CompressedFileInfo) Name() string       { return f.name }
 func (f *vfsgen۰CompressedFileInfo) Size() int64        { return f.uncompressedSize }
-func (f *vfsgen۰CompressedFileInfo) Mode() os.FileMode  { return 0444 }
+func (f *vfsgen۰CompressedFileInfo) Mode() os.FileMode  { return f.mode }
 func (f *vfsgen۰CompressedFileInfo) ModTime() time.Time { return f.modTime }
 func (f *vfsgen۰CompressedFileInfo) IsDir() bool        { return false }
-func (f *vfsgen۰CompressedFileInfo) Sys() interface{}   { return nil }
+func (f *vfsgen۰CompressedFileInfo) Sys() interface{}   { return f.meta }
 
 // vfsgen۰CompressedFile is an opened compressedFile instance.
 type vfsgen۰CompressedFile struct {
@@ -394,7 +791,9 @@ var _ = ioutil.Discard
 type vfsgen۰FileInfo struct {
 	name    string
 	modTime time.Time
+	mode    os.FileMode
 	content []byte
+	meta    *vfsgen۰FileMeta
 }
 
 func (f *vfsgen۰FileInfo) Readdir(count int) ([]os.FileInfo, error) {
@@ -404,12 +803,16 @@ func (f *vfsgen۰FileInfo) Stat() (os.FileInfo, error) { return f, nil }
 
 func (f *vfsgen۰FileInfo) NotWorthGzipCompressing() {}
 
+func (f *vfsgen۰FileInfo) ContentType() string {
+	return f.meta.ContentType
+}
+
 func (f *vfsgen۰FileInfo) Name() string       { return f.name }
 func (f *vfsgen۰FileInfo) Size() int64        { return int64(len(f.content)) }
-func (f *vfsgen۰FileInfo) Mode() os.FileMode  { return 0444 }
+func (f *vfsgen۰FileInfo) Mode() os.FileMode  { return f.mode }
 func (f *vfsgen۰FileInfo) ModTime() time.Time { return f.modTime }
 func (f *vfsgen۰FileInfo) IsDir() bool        { return false }
-func (f *vfsgen۰FileInfo) Sys() interface{}   { return nil }
+func (f *vfsgen۰FileInfo) Sys() interface{}   { return f.meta }
 
 // vfsgen۰File is an opened file instance.
 type vfsgen۰File struct {
@@ -423,11 +826,23 @@ func (f *vfsgen۰File) Close() error {
 {{else if not .HasCompressedFile}}
 // We already imported "bytes", but ended up not using it. Avoid unused import error.
 var _ = bytes.Reader{}
+{{end}}{{if or .HasCompressedFile .HasFile}}
+// vfsgen۰FileMeta is the metadata of a file, returned by Sys.
+// It's identical to vfs.FileMeta, use vfs.Meta to get it.
+type vfsgen۰FileMeta = struct {
+	ContentType    string            ` + "`" + `json:"contentType,omitempty"` + "`" + `
+	Hash           string            ` + "`" + `json:"hash,omitempty"` + "`" + `
+	CompressedSize int64             ` + "`" + `json:"compressedSize,omitempty"` + "`" + `
+	Encoding       string            ` + "`" + `json:"encoding,omitempty"` + "`" + `
+	Source         string            ` + "`" + `json:"source,omitempty"` + "`" + `
+	Values         map[string]string ` + "`" + `json:"values,omitempty"` + "`" + `
+}
 {{end}}
 // vfsgen۰DirInfo is a static definition of a directory.
 type vfsgen۰DirInfo struct {
 	name    string
 	modTime time.Time
+	mode    os.FileMode
 	entries []os.FileInfo
 }
 
@@ -439,7 +854,7 @@ func (d *vfsgen۰DirInfo) Stat() (os.FileInfo, error) { return d, nil }
 
 func (d *vfsgen۰DirInfo) Name() string       { return d.name }
 func (d *vfsgen۰DirInfo) Size() int64        { return 0 }
-func (d *vfsgen۰DirInfo) Mode() os.FileMode  { return 0755 | os.ModeDir }
+func (d *vfsgen۰DirInfo) Mode() os.FileMode  { return d.mode | os.ModeDir }
 func (d *vfsgen۰DirInfo) ModTime() time.Time { return d.modTime }
 func (d *vfsgen۰DirInfo) IsDir() bool        { return true }
 func (d *vfsgen۰DirInfo) Sys() interface{}   { return nil }
@@ -1,15 +1,23 @@
 package vfs_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
 	"github.com/gozelle/vfs"
 	"io/ioutil"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 	
 	"github.com/shurcooL/httpfs/union"
 	"golang.org/x/tools/godoc/vfs/httpfs"
@@ -35,19 +43,12 @@ func Example() {
 // Verify that all possible combinations of {non-compressed,compressed} files build
 // successfully, and have no gofmt issues.
 func TestGenerate_buildAndGofmt(t *testing.T) {
-	tempDir, err := ioutil.TempDir("", "vfsgen_test_")
-	if err != nil {
-		t.Fatal(err)
-	}
-	defer func() {
-		if err := os.RemoveAll(tempDir); err != nil {
-			t.Fatal(err)
-		}
-	}()
+	tempDir := t.TempDir()
 	
 	tests := []struct {
 		filename  string
 		fs        http.FileSystem
+		opt       vfs.Options
 		wantError func(error) bool // Nil function means want nil error.
 	}{
 		{
@@ -84,15 +85,56 @@ func TestGenerate_buildAndGofmt(t *testing.T) {
 				"compressable-file.txt":     "This text compresses easily. " + strings.Repeat(" Go!", 128),
 			})),
 		},
+		{
+			// Preserved symbolic links.
+			filename: "symlinks.go",
+			fs:       symlinkFS(t),
+			opt:      vfs.Options{Symlinks: vfs.PreserveSymlinks},
+		},
+		{
+			// Followed symbolic links.
+			filename: "followed.go",
+			fs:       symlinkFS(t),
+		},
+		{
+			// Empty directories.
+			filename: "emptydirs.go",
+			fs:       emptyDirsFS(t),
+		},
+		{
+			// Recorded file modes.
+			filename: "modes.go",
+			fs:       modeFS(),
+		},
+		{
+			// Normalized file modes.
+			filename: "normalized.go",
+			fs:       modeFS(),
+			opt:      vfs.Options{Mode: vfs.NormalizeMode},
+		},
+		{
+			// Metadata values.
+			filename: "values.go",
+			fs:       modeFS(),
+			opt: vfs.Options{MetaValues: func(path string) map[string]string {
+				return map[string]string{"path": path, "owner": "web"}
+			}},
+		},
+		{
+			// Test that vfsgen.Generate fails on symbolic links when asked to.
+			filename:  "rejected.go",
+			fs:        symlinkFS(t),
+			opt:       vfs.Options{Symlinks: vfs.RejectSymlinks},
+			wantError: func(err error) bool { return err != nil },
+		},
 	}
 	
 	for _, test := range tests {
 		filename := filepath.Join(tempDir, test.filename)
 		
-		err := vfs.Generate(test.fs, vfs.Options{
-			Filename:    filename,
-			PackageName: "test",
-		})
+		opt := test.opt
+		opt.Filename, opt.PackageName = filename, "test"
+		err := vfs.Generate(test.fs, opt)
 		switch {
 		case test.wantError == nil && err != nil:
 			t.Fatalf("%s: vfsgen.Generate returned non-nil error: %v", test.filename, err)
@@ -111,3 +153,511 @@ func TestGenerate_buildAndGofmt(t *testing.T) {
 		}
 	}
 }
+
+// symlinkFS returns a filesystem with symbolic links to a file and to a directory.
+func symlinkFS(t *testing.T) http.FileSystem {
+	fs := vfs.NewFS()
+	fs.Add("/v3", "app.js", []byte("console.log('v3');"))
+	for target, link := range map[string]string{"v3": "/latest", "/v3/app.js": "/app.js"} {
+		if err := fs.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+// emptyDirsFS returns a filesystem with empty directories.
+func emptyDirsFS(t *testing.T) http.FileSystem {
+	fs := vfs.NewFS()
+	fs.Add("/", "file.txt", []byte("Not empty."))
+	if err := fs.MkdirAll("/empty/nested"); err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+// modeFS returns a filesystem with an executable and a private file.
+func modeFS() http.FileSystem {
+	fs := vfs.NewFS()
+	_ = fs.AddFile("/bin/run.sh", []byte("#!/bin/sh"), vfs.AddFileOptions{Mode: 0755})
+	_ = fs.AddFile("/secret.txt", []byte("Private."), vfs.AddFileOptions{Mode: 0600})
+	return fs
+}
+
+// generate returns the code generated by Generate for fs with opt.
+func generate(t testing.TB, fs http.FileSystem, opt vfs.Options) []byte {
+	t.Helper()
+	opt.Filename = filepath.Join(t.TempDir(), "assets.go")
+	if err := vfs.Generate(fs, opt); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(opt.Filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// generateInput is an input of Generate.
+type generateInput struct {
+	fs  http.FileSystem
+	opt vfs.Options
+}
+
+// generatedFile is a file or directory as served by the code generated by Generate.
+type generatedFile struct {
+	IsDir       bool
+	Mode        os.FileMode
+	ModTime     time.Time
+	ContentType string
+	Hash        string
+	Content     []byte   // Content of a file.
+	Compressed  bool     // Whether a file provides its gzip compressed content.
+	Entries     []string // Names of the entries of a directory.
+}
+
+// loadProgram walks the generated filesystems, listed in place of /*fss*/,
+// and prints their files and directories as JSON.
+const loadProgram = `package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"time"
+/*imports*/)
+
+type file struct {
+	IsDir       bool
+	Mode        os.FileMode
+	ModTime     time.Time
+	ContentType string
+	Hash        string
+	Content     []byte
+	Compressed  bool
+	Entries     []string
+}
+
+func walk(fs http.FileSystem, path string, files map[string]file) error {
+	f, err := fs.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r := file{IsDir: fi.IsDir(), Mode: fi.Mode(), ModTime: fi.ModTime()}
+	if ct, ok := f.(interface{ ContentType() string }); ok {
+		r.ContentType = ct.ContentType()
+	}
+	var meta struct{ Hash string }
+	if b, err := json.Marshal(fi.Sys()); err == nil {
+		_ = json.Unmarshal(b, &meta)
+	}
+	r.Hash = meta.Hash
+	if fi.IsDir() {
+		fis, err := f.Readdir(0)
+		if err != nil {
+			return err
+		}
+		for _, e := range fis {
+			r.Entries = append(r.Entries, e.Name())
+			if err := walk(fs, pathpkg.Join(path, e.Name()), files); err != nil {
+				return err
+			}
+		}
+	} else {
+		b, err := ioutil.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		r.Content = b
+		_, r.Compressed = f.(interface{ GzipBytes() []byte })
+	}
+	files[path] = r
+	return nil
+}
+
+func main() {
+	var out []map[string]file
+	for _, fs := range []http.FileSystem{/*fss*/} {
+		files := map[string]file{}
+		if err := walk(fs, "/", files); err != nil {
+			panic(err)
+		}
+		out = append(out, files)
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(out); err != nil {
+		panic(err)
+	}
+}
+`
+
+// load generates code for each of inputs, builds a program using it, and returns
+// the files and directories served by each generated filesystem, keyed by path.
+func load(t *testing.T, inputs ...generateInput) []map[string]generatedFile {
+	t.Helper()
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module generated\n\ngo 1.18\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var imports, fss []string
+	for i, in := range inputs {
+		pkg := fmt.Sprintf("p%d", i)
+		opt := in.opt
+		opt.Filename = filepath.Join(dir, pkg, "assets.go")
+		opt.PackageName, opt.VariableName = pkg, "Assets"
+		if err := os.Mkdir(filepath.Dir(opt.Filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := vfs.Generate(in.fs, opt); err != nil {
+			t.Fatalf("input %d: %v", i, err)
+		}
+		imports = append(imports, fmt.Sprintf("\t%q\n", "generated/"+pkg))
+		fss = append(fss, pkg+".Assets")
+	}
+	program := strings.NewReplacer("/*imports*/", strings.Join(imports, ""), "/*fss*/", strings.Join(fss, ", ")).Replace(loadProgram)
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(program), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("running generated code: %v\n%s", err, stderr.Bytes())
+	}
+	var files []map[string]generatedFile
+	if err := json.Unmarshal(out, &files); err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// paths returns the sorted paths of files.
+func paths(files map[string]generatedFile) string {
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, ",")
+}
+
+func TestGenerate_modes(t *testing.T) {
+	keep := func(path string, mode os.FileMode) os.FileMode { return mode.Perm() }
+	tests := []struct {
+		opt                 vfs.Options
+		wantRun, wantSecret os.FileMode
+	}{
+		{vfs.Options{}, 0755, 0600},
+		{vfs.Options{Mode: vfs.NormalizeMode}, 0755, 0644},
+		{vfs.Options{Reproducible: true}, 0755, 0644}, // Normalized by default.
+		{vfs.Options{Reproducible: true, Mode: keep}, 0755, 0600},
+	}
+	var inputs []generateInput
+	for _, test := range tests {
+		inputs = append(inputs, generateInput{modeFS(), test.opt})
+	}
+	for i, files := range load(t, inputs...) {
+		test := tests[i]
+		if got := files["/bin/run.sh"].Mode; got != test.wantRun {
+			t.Errorf("%d: /bin/run.sh mode: got %v, want %v", i, got, test.wantRun)
+		}
+		if got := files["/secret.txt"].Mode; got != test.wantSecret {
+			t.Errorf("%d: /secret.txt mode: got %v, want %v", i, got, test.wantSecret)
+		}
+		if got, want := files["/bin"].Mode, 0755|os.ModeDir; got != want {
+			t.Errorf("%d: /bin mode: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestGenerate_contentTypes(t *testing.T) {
+	fs := vfs.NewFS()
+	fs.Add("/", "page", []byte("<!DOCTYPE html><p>Hello.</p>"))
+	fs.Add("/", "data.bin", []byte("{}"))
+	files := load(t, generateInput{fs, vfs.Options{
+		ContentTypes: []vfs.ContentTypeRule{{Pattern: "*.bin", ContentType: "application/json"}},
+	}})[0]
+	for path, want := range map[string]string{
+		"/page":     "text/html; charset=utf-8",
+		"/data.bin": "application/json",
+	} {
+		if got := files[path].ContentType; got != want {
+			t.Errorf("%s: got content type %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestGenerate_filter(t *testing.T) {
+	fs := vfs.NewFS()
+	fs.Add("/", ".vfsignore", []byte("# Fixtures.\ntestdata/\n"))
+	fs.Add("/", ".DS_Store", []byte("junk"))
+	fs.Add("/js", "app.js", []byte("console.log('hi');"))
+	fs.Add("/js", "app.js.map", []byte("{}"))
+	fs.Add("/js", "vendor.js", []byte("// Vendored."))
+	fs.Add("/css", "site.css", []byte("p { color: red; }"))
+	fs.Add("/testdata", "fixture.js", []byte("// Fixture."))
+	_ = fs.MkdirAll("/uploads")
+	
+	tests := []struct {
+		opt         vfs.Options
+		wantPaths   string
+		wantSkipped []string
+	}{
+		{
+			opt:         vfs.Options{Exclude: []string{"*.map", ".DS_Store"}},
+			wantPaths:   "/,/css,/css/site.css,/js,/js/app.js,/js/vendor.js,/uploads",
+			wantSkipped: []string{"/.DS_Store", "/js/app.js.map", "/testdata"},
+		},
+		{
+			opt:         vfs.Options{Include: []string{"*.js", "!vendor.js"}},
+			wantPaths:   "/,/js,/js/app.js,/uploads",
+			wantSkipped: []string{"/.DS_Store", "/css", "/js/app.js.map", "/js/vendor.js", "/testdata"},
+		},
+	}
+	skipped := make([][]string, len(tests))
+	var inputs []generateInput
+	for i, test := range tests {
+		i, opt := i, test.opt
+		opt.Skipped = func(paths []string) { skipped[i] = paths }
+		inputs = append(inputs, generateInput{fs, opt})
+	}
+	for i, files := range load(t, inputs...) {
+		test := tests[i]
+		if got := paths(files); got != test.wantPaths {
+			t.Errorf("%v: generated %q, want %q", test.opt, got, test.wantPaths)
+		}
+		if got, want := strings.Join(skipped[i], ","), strings.Join(test.wantSkipped, ","); got != want {
+			t.Errorf("%v: skipped %q, want %q", test.opt, got, want)
+		}
+	}
+}
+
+func TestGenerate_reproducible(t *testing.T) {
+	// Checkouts of the same files, made at different times.
+	checkout := func(mtime time.Time) http.FileSystem {
+		root := t.TempDir()
+		files := map[string]string{
+			"index.html":     "<p>Hello.</p>",
+			"js/app.js":      "console.log('hi');" + strings.Repeat(" ", 100),
+			"css/site.css":   "p { color: red; }",
+			"css/extra.css":  "",
+			"img/.gitignore": "*",
+		}
+		for path, content := range files {
+			filename := filepath.Join(root, filepath.FromSlash(path))
+			if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return os.Chtimes(path, mtime, mtime)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return http.Dir(root)
+	}
+	
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := generate(t, checkout(mtime), vfs.Options{Reproducible: true})
+	b := generate(t, checkout(mtime.Add(time.Hour)), vfs.Options{Reproducible: true})
+	if !bytes.Equal(a, b) {
+		t.Error("reproducible generations from trees with different mod times differ")
+	}
+	
+	for _, test := range []struct {
+		epoch string
+		want  time.Time
+	}{
+		{"", time.Time{}},
+		{"1577836800", mtime},
+	} {
+		t.Setenv("SOURCE_DATE_EPOCH", test.epoch)
+		for path, f := range load(t, generateInput{checkout(mtime.Add(2 * time.Hour)), vfs.Options{Reproducible: true}})[0] {
+			if !f.ModTime.Equal(test.want) {
+				t.Errorf("SOURCE_DATE_EPOCH=%q: %s: got mod time %v, want %v", test.epoch, path, f.ModTime, test.want)
+			}
+		}
+	}
+	
+	t.Setenv("SOURCE_DATE_EPOCH", "yesterday")
+	if err := vfs.Generate(checkout(mtime), vfs.Options{Filename: filepath.Join(t.TempDir(), "assets.go"), Reproducible: true}); err == nil {
+		t.Error("Generate with invalid SOURCE_DATE_EPOCH: got nil error")
+	}
+}
+
+func TestGenerate_compression(t *testing.T) {
+	level := func(l int) *int { return &l }
+	compressible := strings.Repeat("Go! ", 256)
+	contents := map[string]string{
+		"/text.txt":  compressible,
+		"/image.png": compressible,
+		"/image":     "\x89PNG\r\n\x1a\n" + compressible,
+		"/photo":     "RIFF\x10\x27\x00\x00WEBPVP8 " + compressible,
+		"/sound":     "RIFF\x10\x27\x00\x00WAVEfmt " + compressible,
+		"/small.txt": "Hello, Hello, Hello, Hello, Hello.",
+		"/skip.txt":  compressible,
+	}
+	fs := vfs.NewFS()
+	for path, content := range contents {
+		fs.Add("/", path[1:], []byte(content))
+	}
+	
+	tests := []struct {
+		compression    vfs.CompressionOptions
+		wantCompressed string
+	}{
+		{
+			compression:    vfs.CompressionOptions{},
+			wantCompressed: "/skip.txt,/small.txt,/sound,/text.txt",
+		},
+		{
+			compression:    vfs.CompressionOptions{Level: level(gzip.BestSpeed), MinSavings: 16},
+			wantCompressed: "/skip.txt,/sound,/text.txt",
+		},
+		{
+			compression:    vfs.CompressionOptions{MinSavingsPercent: 50},
+			wantCompressed: "/skip.txt,/sound,/text.txt",
+		},
+		{
+			// Stored content is never smaller than the original.
+			compression:    vfs.CompressionOptions{Level: level(gzip.NoCompression)},
+			wantCompressed: "",
+		},
+		{
+			compression:    vfs.CompressionOptions{Level: level(gzip.DefaultCompression)},
+			wantCompressed: "/skip.txt,/sound,/text.txt",
+		},
+		{
+			compression:    vfs.CompressionOptions{Level: level(gzip.HuffmanOnly)},
+			wantCompressed: "/skip.txt,/sound,/text.txt",
+		},
+		{
+			compression: vfs.CompressionOptions{
+				ShouldCompress: func(path string, fi os.FileInfo) bool { return path != "/skip.txt" },
+				SkipExtensions: []string{},
+				SkipMagic:      []vfs.Magic{},
+			},
+			wantCompressed: "/image,/image.png,/photo,/small.txt,/sound,/text.txt",
+		},
+	}
+	var inputs []generateInput
+	for _, test := range tests {
+		inputs = append(inputs, generateInput{fs, vfs.Options{Compression: test.compression}})
+	}
+	for i, files := range load(t, inputs...) {
+		test := tests[i]
+		compressed := map[string]generatedFile{}
+		for path, f := range files {
+			if f.IsDir {
+				continue
+			}
+			if string(f.Content) != contents[path] {
+				t.Errorf("%d: %s: got content %q, want %q", i, path, f.Content, contents[path])
+			}
+			if f.Compressed {
+				compressed[path] = f
+			}
+		}
+		if got := paths(compressed); got != test.wantCompressed {
+			t.Errorf("%d: compressed %q, want %q", i, got, test.wantCompressed)
+		}
+	}
+	
+	for _, c := range []vfs.CompressionOptions{
+		{Level: level(42)},
+		{Level: level(gzip.HuffmanOnly - 1)},
+		{SkipMagic: []vfs.Magic{{Bytes: []byte("ab"), Mask: []byte("\xff")}}},
+	} {
+		err := vfs.Generate(fs, vfs.Options{Filename: filepath.Join(t.TempDir(), "assets.go"), Compression: c})
+		if err == nil {
+			t.Errorf("Generate with invalid compression options %+v: got nil error", c)
+		}
+	}
+}
+
+// assetTreeFS returns a filesystem with n compressible files of size bytes each.
+func assetTreeFS(n, size int) http.FileSystem {
+	fs := vfs.NewFS()
+	rnd := rand.New(rand.NewSource(1))
+	words := []string{"vfs ", "static ", "assets ", "compress ", "generate ", "http "}
+	for i := 0; i < n; i++ {
+		var b bytes.Buffer
+		for b.Len() < size {
+			b.WriteString(words[rnd.Intn(len(words))])
+		}
+		fs.Add(fmt.Sprintf("/dir%d", i%8), fmt.Sprintf("file%d.txt", i), b.Bytes())
+	}
+	return fs
+}
+
+func TestGenerate_concurrency(t *testing.T) {
+	const n = 4
+	fs := assetTreeFS(64, 4<<10)
+	
+	// The first n writers wait for each other, which they can only do if they run concurrently.
+	var (
+		mu      sync.Mutex
+		started int
+		all     = make(chan struct{})
+		timeout = make(chan struct{})
+	)
+	timer := time.AfterFunc(10*time.Second, func() { close(timeout) })
+	defer timer.Stop()
+	restore := vfs.SetTestHookWriteFile(func() {
+		mu.Lock()
+		started++
+		if started == n {
+			close(all)
+		}
+		wait := started <= n
+		mu.Unlock()
+		if wait {
+			select {
+			case <-all:
+			case <-timeout:
+			}
+		}
+	})
+	parallel := generate(t, fs, vfs.Options{Concurrency: n})
+	restore()
+	select {
+	case <-all:
+	default:
+		t.Errorf("the files weren't written by %d goroutines at once", n)
+	}
+	
+	sequential := generate(t, fs, vfs.Options{Concurrency: 1})
+	if !bytes.Equal(sequential, parallel) {
+		t.Error("output depends on concurrency")
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	fs := assetTreeFS(64, 32<<10)
+	filename := filepath.Join(b.TempDir(), "assets.go")
+	for _, concurrency := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("Concurrency=%d", concurrency), func(b *testing.B) {
+			opt := vfs.Options{Filename: filename, Concurrency: concurrency}
+			for i := 0; i < b.N; i++ {
+				if err := vfs.Generate(fs, opt); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package vfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	pathpkg "path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitModTime returns an Options.ModTime function for an input filesystem reading the
+// directory dir of a git working tree, such as http.Dir(dir). It records the time of
+// the last commit changing each file, or anything inside each directory, as reported
+// by the git binary. Paths without commits, such as untracked files, get fallback.
+// The history is read once, on the first call.
+func GitModTime(dir string, fallback time.Time) func(path string, fi os.FileInfo) (time.Time, error) {
+	var (
+		once  sync.Once
+		times map[string]time.Time
+		err   error
+	)
+	return func(path string, fi os.FileInfo) (time.Time, error) {
+		once.Do(func() {
+			times, err = gitLogTimes(dir)
+		})
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t, ok := times[path]; ok {
+			return t, nil
+		}
+		return fallback, nil
+	}
+}
+
+// gitLogTimes returns the time of the last commit changing each path inside dir,
+// keyed by slash separated path relative to dir, starting with "/". The time of
+// a directory is the one of the last commit changing anything inside it.
+func gitLogTimes(dir string) (map[string]time.Time, error) {
+	// Each commit is a NUL prefixed line with its time, followed by the names of the files it changed.
+	cmd := exec.Command("git", "-c", "core.quotepath=off", "log", "--name-only", "--format=%x00%ct", "--relative", "--", ".")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return nil, fmt.Errorf("git log: %v: %s", err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return nil, fmt.Errorf("git log: %v", err)
+	}
+	
+	times := map[string]time.Time{}
+	var commit time.Time
+	s := bufio.NewScanner(bytes.NewReader(out))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		line := s.Text()
+		switch {
+		case line == "":
+			continue
+		case line[0] == 0:
+			sec, err := strconv.ParseInt(line[1:], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("git log: unexpected output %q", line)
+			}
+			commit = time.Unix(sec, 0)
+			continue
+		case line[0] == '"':
+			// Names with special characters are quoted C style.
+			if line, err = strconv.Unquote(line); err != nil {
+				return nil, fmt.Errorf("git log: unexpected output %q", s.Text())
+			}
+		}
+		// Keep the time of the last commit changing the path, or anything inside it.
+		for p := pathpkg.Clean("/" + line); ; p = pathpkg.Dir(p) {
+			if t, ok := times[p]; !ok || commit.After(t) {
+				times[p] = commit
+			}
+			if p == "/" {
+				break
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("git log: %v", err)
+	}
+	return times, nil
+}
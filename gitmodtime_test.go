@@ -0,0 +1,91 @@
+package vfs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGitModTime(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available:", err)
+	}
+	dir, err := ioutil.TempDir("", "vfs_git_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	
+	git := func(date string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com", "GIT_AUTHOR_DATE="+date,
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_COMMITTER_DATE="+date,
+		)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	
+	git("", "init", "-q")
+	write("css/site.css", "p { color: red; }")
+	write("index.html", "<p>Hello.</p>")
+	write("docs/say \"hi\".txt", "Hi.")
+	git("2020-01-01T00:00:00Z", "add", ".")
+	git("2020-01-01T00:00:00Z", "commit", "-q", "-m", "Initial.")
+	write("css/site.css", "p { color: blue; }")
+	git("2021-06-01T12:00:00Z", "commit", "-q", "-a", "-m", "Blue.")
+	write("draft.html", "<p>Draft.</p>")
+	
+	fallback := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	modTime := GitModTime(dir, fallback)
+	for path, want := range map[string]time.Time{
+		"/":                    time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+		"/css":                 time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+		"/css/site.css":        time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+		"/index.html":          time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"/docs":                time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"/docs/say \"hi\".txt": time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		"/draft.html":          fallback,
+	} {
+		got, err := modTime(path, nil)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("%s: got %v, %v, want %v", path, got, err, want)
+		}
+	}
+	
+	filename := filepath.Join(dir, "assets.go")
+	err = Generate(http.Dir(dir), Options{Filename: filename, Exclude: []string{"/.git/"}, ModTime: modTime})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)") {
+		t.Error("generated code doesn't contain mod times from git history")
+	}
+	if _, err := GitModTime(filepath.Join(dir, "missing"), fallback)("/", nil); err == nil {
+		t.Error("missing directory: got nil error")
+	}
+}
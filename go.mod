@@ -5,10 +5,7 @@ go 1.18
 require (
 	github.com/shurcooL/httpfs v0.0.0-20190707220628-8d4bc4ba7749
 	github.com/shurcooL/httpgzip v0.0.0-20190720172056-320755c1c1b0
+	golang.org/x/net v0.5.0
+	golang.org/x/text v0.6.0
 	golang.org/x/tools v0.5.0
 )
-
-require (
-	golang.org/x/net v0.5.0 // indirect
-	golang.org/x/text v0.6.0 // indirect
-)
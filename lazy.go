@@ -0,0 +1,226 @@
+package vfs
+
+import (
+	"os"
+	pathpkg "path"
+	"sync"
+	"time"
+)
+
+// AddFuncOptions configures a file added with FS.AddFunc.
+type AddFuncOptions struct {
+	// Size is the size of the content, known in advance. It lets Stat and
+	// directory listings report the size without producing the content.
+	// If it's unknown, set it to -1 for the content to be produced when
+	// the size is needed. If left zero, the file is declared empty.
+	Size int64
+	
+	// ModTime is the modification time of the file.
+	// If left zero, the time of the call to AddFunc or Invalidate is used.
+	ModTime time.Time
+	
+	// Mode holds the permission bits of the file.
+	// If left zero, it defaults to 0444.
+	Mode os.FileMode
+	
+	// ContentType is the MIME type of the file.
+	// If left empty, it's detected from the extension or the produced content.
+	ContentType string
+	
+	// Source is the path the file is read from, reported in its FileMeta.
+	Source string
+	
+	// Values are arbitrary values reported in the FileMeta of the file.
+	Values map[string]string
+}
+
+// AddFunc adds a file at path whose content is produced by calling producer when the
+// file is first opened. The content is then compressed and kept until Invalidate is
+// called. Errors returned by producer are returned by Open, and aren't kept.
+// While the content can't be produced, the size of a file without a declared
+// size is reported as 0. The parent directory must already exist.
+func (fs *FS) AddFunc(path string, producer func() ([]byte, error), opt AddFuncOptions) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	path = fs.resolveParent(path)
+	if _, ok := fs.paths[path].(*DirInfo); ok {
+		return &os.PathError{Op: "write", Path: path, Err: errIsDir}
+	}
+	if err := fs.checkParent("write", path); err != nil {
+		return err
+	}
+	if opt.Size < -1 {
+		return &os.PathError{Op: "write", Path: path, Err: os.ErrInvalid}
+	}
+	modTime := opt.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+	fs.version++
+	return fs.put(path, &lazyFileInfo{
+		name:     pathpkg.Base(path),
+		modTime:  modTime,
+		size:     opt.Size,
+		mode:     opt.Mode.Perm(),
+		ctype:    opt.ContentType,
+		source:   opt.Source,
+		values:   opt.Values,
+		producer: producer,
+		fixedMod: !opt.ModTime.IsZero(),
+		blob:     &blob{},
+	})
+}
+
+// Invalidate discards the produced content of the file at path, which must have
+// been added with AddFunc, so that it's produced again when next opened.
+// Paths linked to it with Link are invalidated too.
+func (fs *FS) Invalidate(path string) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	path = fs.resolve(path)
+	f, ok := fs.paths[path].(*lazyFileInfo)
+	if !ok {
+		return &os.PathError{Op: "invalidate", Path: path, Err: os.ErrInvalid}
+	}
+	paths := []string{path}
+	if fs.links[f.blob] > 1 {
+		paths = paths[:0]
+		for p, v := range fs.paths {
+			if l, ok := v.(*lazyFileInfo); ok && l.blob == f.blob {
+				paths = append(paths, p)
+			}
+		}
+	}
+	fs.version++
+	b, now := &blob{}, time.Now()
+	for _, p := range paths {
+		f := fs.paths[p].(*lazyFileInfo)
+		fresh := &lazyFileInfo{name: f.name, modTime: f.modTime, size: f.size, mode: f.mode, ctype: f.ctype, source: f.source, values: f.values, producer: f.producer, fixedMod: f.fixedMod, blob: b}
+		if !f.fixedMod {
+			fresh.modTime = now
+		}
+		if err := fs.put(p, fresh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lazyFileInfo is the definition of a file whose content is produced on demand.
+// It's replaced rather than reset when invalidated.
+type lazyFileInfo struct {
+	name     string
+	modTime  time.Time
+	size     int64       // Declared size, -1 if unknown.
+	mode     os.FileMode // Permission bits, zero for the default 0444.
+	ctype    string      // Given MIME type, empty to detect it.
+	source   string
+	values   map[string]string
+	producer func() ([]byte, error)
+	fixedMod bool  // Whether modTime was given, rather than the time of the last invalidation.
+	blob     *blob // Produced content, shared with the paths linked to this file.
+	
+	mu   sync.Mutex
+	file *CompressedFileInfo // Produced content named after this file, nil until produced.
+}
+
+// produce returns the compressed content of the file, producing it if needed.
+func (f *lazyFileInfo) produce() (*CompressedFileInfo, error) {
+	f.mu.Lock()
+	defer func() {
+		f.mu.Unlock()
+	}()
+	
+	if f.file != nil {
+		return f.file, nil
+	}
+	shared, err := f.produceBlob()
+	if err != nil {
+		return nil, err
+	}
+	cf := *shared
+	cf.name, cf.modTime, cf.mode = f.name, f.modTime, f.mode
+	f.file = &cf
+	return f.file, nil
+}
+
+// produceBlob returns the content shared by the links of the file, producing it if needed.
+func (f *lazyFileInfo) produceBlob() (*CompressedFileInfo, error) {
+	f.blob.mu.Lock()
+	defer func() {
+		f.blob.mu.Unlock()
+	}()
+	
+	if f.blob.file != nil {
+		return f.blob.file, nil
+	}
+	content, err := f.producer()
+	if err != nil {
+		return nil, err
+	}
+	cf := newCompressedFileInfo(f.name, content)
+	cf.meta.Source, cf.meta.Values = f.source, f.values
+	if f.ctype != "" {
+		cf.meta.ContentType = f.ctype
+	}
+	f.blob.file = cf
+	return cf, nil
+}
+
+// renamed returns a copy of f with a different name, sharing the produced content.
+func (f *lazyFileInfo) renamed(name string) *lazyFileInfo {
+	f.mu.Lock()
+	defer func() {
+		f.mu.Unlock()
+	}()
+	
+	c := &lazyFileInfo{name: name, modTime: f.modTime, size: f.size, mode: f.mode, ctype: f.ctype, source: f.source, values: f.values, producer: f.producer, fixedMod: f.fixedMod, blob: f.blob}
+	if f.file != nil {
+		c.file = renamed(f.file, name).(*CompressedFileInfo)
+	}
+	return c
+}
+
+func (f *lazyFileInfo) Name() string { return f.name }
+func (f *lazyFileInfo) Size() int64 {
+	if f.size >= 0 {
+		return f.size
+	}
+	cf, err := f.produce()
+	if err != nil {
+		// Open reports the error, there's no size to report until then.
+		return 0
+	}
+	return cf.uncompressedSize
+}
+func (f *lazyFileInfo) Mode() os.FileMode {
+	if f.mode == 0 {
+		return 0444
+	}
+	return f.mode
+}
+func (f *lazyFileInfo) ModTime() time.Time { return f.modTime }
+func (f *lazyFileInfo) IsDir() bool        { return false }
+
+// Sys returns the FileMeta of the produced content, or nil if it isn't produced yet.
+func (f *lazyFileInfo) Sys() interface{} {
+	f.blob.mu.Lock()
+	defer func() {
+		f.blob.mu.Unlock()
+	}()
+	
+	if f.blob.file == nil {
+		return nil
+	}
+	return f.blob.file.Sys()
+}
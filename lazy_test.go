@@ -0,0 +1,104 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestAddFunc(t *testing.T) {
+	fs := NewFS()
+	calls := 0
+	setting := "dark"
+	err := fs.AddFunc("/config.js", func() ([]byte, error) {
+		calls++
+		return []byte(fmt.Sprintf("theme = %q", setting)), nil
+	}, AddFuncOptions{Size: 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	fi, err := fs.Stat("/config.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 14 || calls != 0 {
+		t.Errorf("Stat: got size %d after %d calls, want declared size without calls", fi.Size(), calls)
+	}
+	for i := 0; i < 2; i++ {
+		if got := readFileT(t, fs, "/config.js"); got != `theme = "dark"` {
+			t.Errorf("got %q", got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want 1", calls)
+	}
+	
+	setting = "light"
+	version := fs.Version()
+	if err := fs.Invalidate("/config.js"); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Version() == version {
+		t.Error("Invalidate didn't change the version")
+	}
+	if got := readFileT(t, fs, "/config.js"); got != `theme = "light"` {
+		t.Errorf("got %q after Invalidate", got)
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+	
+	fs.Add("/", "static.txt", nil)
+	if err := fs.Invalidate("/static.txt"); err == nil {
+		t.Error("Invalidate of a regular file: got nil error")
+	}
+	if err := fs.AddFunc("/missing/sitemap.xml", nil, AddFuncOptions{}); !os.IsNotExist(err) {
+		t.Errorf("AddFunc in missing directory: got %v", err)
+	}
+	if err := fs.AddFunc("/negative.txt", nil, AddFuncOptions{Size: -2}); err == nil {
+		t.Error("AddFunc with a negative size other than -1: got nil error")
+	}
+	
+	calls = 0
+	err = fs.AddFunc("/empty.txt", func() ([]byte, error) {
+		calls++
+		return nil, nil
+	}, AddFuncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := fs.Stat("/empty.txt"); err != nil || fi.Size() != 0 || calls != 0 {
+		t.Errorf("Stat of a file declared empty: got %v, %v after %d calls, want size 0 without calls", fi, err, calls)
+	}
+}
+
+func TestAddFuncErrors(t *testing.T) {
+	fs := NewFS()
+	errUnavailable := errors.New("state unavailable")
+	fail := true
+	err := fs.AddFunc("/sitemap.xml", func() ([]byte, error) {
+		if fail {
+			return nil, errUnavailable
+		}
+		return []byte("<urlset/>"), nil
+	}, AddFuncOptions{Size: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	if _, err := fs.Open("/sitemap.xml"); !errors.Is(err, errUnavailable) {
+		t.Errorf("got %v, want producer error", err)
+	}
+	if fi, err := fs.Stat("/sitemap.xml"); err != nil || fi.Size() != 0 {
+		t.Errorf("Stat while the producer fails: got %v, %v, want size 0", fi, err)
+	}
+	fail = false
+	if got := readFileT(t, fs, "/sitemap.xml"); got != "<urlset/>" {
+		t.Errorf("got %q after error", got)
+	}
+	if fi, _ := fs.Stat("/sitemap.xml"); fi.Size() != 9 {
+		t.Errorf("got size %d, want size of produced content", fi.Size())
+	}
+}
@@ -0,0 +1,142 @@
+package vfs
+
+import (
+	"os"
+	pathpkg "path"
+	"sync"
+)
+
+// Link adds newpath as another name of the file at existing. Both paths share
+// the stored compressed content, which is kept for as long as any of them
+// exists, so removing or replacing one of them doesn't affect the others.
+// Files added with AddFunc produce their content once for all their paths.
+// The parent directory of newpath must already exist.
+func (fs *FS) Link(existing, newpath string) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	existing = fs.resolve(existing)
+	newpath = fs.resolveParent(newpath)
+	f, ok := fs.paths[existing]
+	switch {
+	case !ok:
+		return &os.LinkError{Op: "link", Old: existing, New: newpath, Err: os.ErrNotExist}
+	case f.(os.FileInfo).IsDir():
+		return &os.LinkError{Op: "link", Old: existing, New: newpath, Err: errIsDir}
+	}
+	if _, ok := fs.paths[newpath]; ok {
+		return &os.LinkError{Op: "link", Old: existing, New: newpath, Err: os.ErrExist}
+	}
+	if err := fs.checkParent("link", newpath); err != nil {
+		return err
+	}
+	if fs.collides(newpath) {
+		return &os.LinkError{Op: "link", Old: existing, New: newpath, Err: ErrNameCollision}
+	}
+	// The puts below can't fail anymore, the version is bumped first
+	// so that the paths they store are recorded with it.
+	fs.version++
+	if cf, ok := f.(*CompressedFileInfo); ok && cf.blob == nil {
+		// Track the content of existing from now on, as it's about to be shared.
+		c := *cf
+		c.blob = &blob{file: cf}
+		f = &c
+		if err := fs.put(existing, f); err != nil {
+			return err
+		}
+	}
+	return fs.put(newpath, renamed(f, pathpkg.Base(newpath)))
+}
+
+// blob is the stored content of a file, shared by the paths created with Link.
+// The FS counts the paths referencing each blob, so that it knows which
+// contents are shared.
+type blob struct {
+	mu   sync.Mutex
+	file *CompressedFileInfo // Stored content, nil until produced for files added with AddFunc.
+}
+
+// blobOf returns the blob of the file definition f, or nil if it has none.
+func blobOf(f interface{}) *blob {
+	switch f := f.(type) {
+	case *CompressedFileInfo:
+		return f.blob
+	case *lazyFileInfo:
+		return f.blob
+	}
+	return nil
+}
+
+// unref drops the reference of the file definition f, which is being removed, to its blob.
+func (fs *FS) unref(f interface{}) {
+	b := blobOf(f)
+	if b == nil {
+		return
+	}
+	if fs.links[b]--; fs.links[b] <= 0 {
+		delete(fs.links, b)
+	}
+}
+
+// FSStats describes the contents of an FS.
+type FSStats struct {
+	Dirs     int // Number of directories, including the root.
+	Files    int // Number of file paths, linked files are counted once per path.
+	Blobs    int // Number of distinct stored contents.
+	Symlinks int // Number of symbolic links.
+	
+	Size       int64 // Uncompressed size of all files, counted once per path.
+	StoredSize int64 // Compressed size of the distinct stored contents.
+	SharedSize int64 // Compressed size saved by files sharing contents through Link.
+}
+
+// Stats returns statistics about the contents of fs. The contents of files
+// added with AddFunc are only accounted for once produced.
+func (fs *FS) Stats() FSStats {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	var s FSStats
+	seen := map[*blob]bool{}
+	for _, v := range fs.paths {
+		var cf *CompressedFileInfo
+		switch f := v.(type) {
+		case *DirInfo:
+			s.Dirs++
+			continue
+		case *symlinkInfo:
+			s.Symlinks++
+			continue
+		case *CompressedFileInfo:
+			cf = f
+		case *lazyFileInfo:
+			f.blob.mu.Lock()
+			cf = f.blob.file
+			f.blob.mu.Unlock()
+		}
+		s.Files++
+		if cf == nil {
+			continue
+		}
+		s.Size += cf.uncompressedSize
+		size := int64(len(cf.compressedContent))
+		if b := blobOf(v); b != nil {
+			if seen[b] {
+				continue
+			}
+			seen[b] = true
+			s.SharedSize += int64(fs.links[b]-1) * size
+		}
+		s.Blobs++
+		s.StoredSize += size
+	}
+	return s
+}
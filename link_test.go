@@ -0,0 +1,98 @@
+package vfs
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/", "index.html", []byte("<h1>Hello</h1>"))
+	fs.Add("/en", "about.html", []byte("about"))
+	if err := fs.Link("/index.html", "/en/index.html"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Link("/index.html", "/fr/index.html"); !os.IsNotExist(err) {
+		t.Errorf("Link into missing directory: got %v", err)
+	}
+	if err := fs.Link("/en", "/de"); err == nil {
+		t.Error("Link of a directory: got nil error")
+	}
+	if err := fs.Link("/index.html", "/en/about.html"); !os.IsExist(err) {
+		t.Errorf("Link over existing file: got %v", err)
+	}
+	
+	s := fs.Stats()
+	if s.Dirs != 2 || s.Files != 3 || s.Blobs != 2 {
+		t.Errorf("got %d dirs, %d files, %d blobs, want 2, 3, 2", s.Dirs, s.Files, s.Blobs)
+	}
+	blob := int64(len(fs.paths["/index.html"].(*CompressedFileInfo).compressedContent))
+	if s.SharedSize != blob {
+		t.Errorf("got shared size %d, want %d", s.SharedSize, blob)
+	}
+	if want := int64(2*len("<h1>Hello</h1>") + len("about")); s.Size != want {
+		t.Errorf("got size %d, want %d", s.Size, want)
+	}
+	
+	b := fs.paths["/index.html"].(*CompressedFileInfo).blob
+	if b == nil || fs.paths["/en/index.html"].(*CompressedFileInfo).blob != b || fs.links[b] != 2 {
+		t.Errorf("linked files don't share a blob referenced twice")
+	}
+	if err := fs.RemoveAll("/index.html"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFileT(t, fs, "/en/index.html"); got != "<h1>Hello</h1>" {
+		t.Errorf("got %q after removing the other link", got)
+	}
+	if fs.links[b] != 1 {
+		t.Errorf("blob referenced %d times after removing a link, want 1", fs.links[b])
+	}
+	if err := fs.WriteFile("/en/index.html", []byte("changed")); err != nil {
+		t.Fatal(err)
+	}
+	if s := fs.Stats(); s.Blobs != 2 || s.SharedSize != 0 {
+		t.Errorf("got %d blobs, shared size %d, want 2, 0", s.Blobs, s.SharedSize)
+	}
+	if _, ok := fs.links[b]; ok {
+		t.Error("blob still referenced after replacing its last path")
+	}
+}
+
+func TestLinkAddFunc(t *testing.T) {
+	fs := NewFS()
+	calls := 0
+	err := fs.AddFunc("/sitemap.xml", func() ([]byte, error) {
+		calls++
+		return []byte(fmt.Sprintf("<urlset>%d</urlset>", calls)), nil
+	}, AddFuncOptions{Size: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Link("/sitemap.xml", "/sitemap-copy.xml"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/sitemap.xml", "/sitemap-copy.xml"} {
+		if got := readFileT(t, fs, path); got != "<urlset>1</urlset>" {
+			t.Errorf("%s: got %q", path, got)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("got %d calls, want the content produced once for both paths", calls)
+	}
+	if s := fs.Stats(); s.Files != 2 || s.Blobs != 1 || s.SharedSize == 0 {
+		t.Errorf("got %d files, %d blobs, shared size %d, want 2 files sharing a blob", s.Files, s.Blobs, s.SharedSize)
+	}
+	
+	if err := fs.Invalidate("/sitemap-copy.xml"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/sitemap.xml", "/sitemap-copy.xml"} {
+		if got := readFileT(t, fs, path); got != "<urlset>2</urlset>" {
+			t.Errorf("%s: got %q after Invalidate", path, got)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("got %d calls, want 2", calls)
+	}
+}
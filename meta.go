@@ -0,0 +1,163 @@
+package vfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	pathpkg "path"
+	"sync"
+	"time"
+)
+
+// FileMeta is the metadata of a file, returned by the Sys method of the
+// os.FileInfo of files in an FS and in the code generated by Generate.
+// Use Meta to get it from an os.FileInfo.
+type FileMeta struct {
+	ContentType    string            `json:"contentType,omitempty"`    // MIME type of the content.
+	Hash           string            `json:"hash,omitempty"`           // Hex encoded SHA-256 of the uncompressed content.
+	CompressedSize int64             `json:"compressedSize,omitempty"` // Size of the stored content if it's encoded.
+	Encoding       string            `json:"encoding,omitempty"`       // Encoding of the stored content, "gzip" or empty if it's stored as is.
+	Source         string            `json:"source,omitempty"`         // Path the file was read from.
+	Values         map[string]string `json:"values,omitempty"`         // Arbitrary values set when the file was added or generated.
+}
+
+// Meta returns the metadata reported by fi.Sys(), or nil if there is none.
+// The first call for a file added to an FS with Add, AddFile or WriteFile
+// computes its hash, which decompresses its whole content.
+func Meta(fi os.FileInfo) *FileMeta {
+	switch m := fi.Sys().(type) {
+	case *FileMeta:
+		return m
+	case *struct {
+		ContentType    string            `json:"contentType,omitempty"`
+		Hash           string            `json:"hash,omitempty"`
+		CompressedSize int64             `json:"compressedSize,omitempty"`
+		Encoding       string            `json:"encoding,omitempty"`
+		Source         string            `json:"source,omitempty"`
+		Values         map[string]string `json:"values,omitempty"`
+	}:
+		// Generated code doesn't import this package, it reports an identical unnamed type.
+		return (*FileMeta)(m)
+	}
+	return nil
+}
+
+// metaDetection completes the metadata of a file from its content when first needed.
+type metaDetection struct {
+	name     string // Name the file was created with, its content type is detected from.
+	typeOnce sync.Once
+	hashOnce sync.Once
+}
+
+// contentType returns the MIME type of f. Files created by newCompressedFileInfo
+// get it, unless given, from their extension or the start of their content on the
+// first call.
+func (f *CompressedFileInfo) contentType() string {
+	if f.meta == nil {
+		return ""
+	}
+	if d := f.detect; d != nil {
+		d.typeOnce.Do(func() {
+			if f.meta.ContentType != "" {
+				return
+			}
+			ctype, err := detectContentType(d.name, f.gzipReader())
+			if err != nil {
+				panic("unexpected error reading own gzip compressed bytes: " + err.Error())
+			}
+			f.meta.ContentType = ctype
+		})
+	}
+	return f.meta.ContentType
+}
+
+// metadata returns the metadata of f, or nil if it's unknown. Files created by
+// newCompressedFileInfo get their content type as by contentType, and their hash
+// from their whole content on the first call, so that files whose metadata isn't
+// used don't pay for it.
+func (f *CompressedFileInfo) metadata() *FileMeta {
+	if d := f.detect; d != nil {
+		f.contentType()
+		d.hashOnce.Do(func() {
+			h := sha256.New()
+			if _, err := io.Copy(h, f.gzipReader()); err != nil {
+				panic("unexpected error reading own gzip compressed bytes: " + err.Error())
+			}
+			f.meta.Hash = hex.EncodeToString(h.Sum(nil))
+		})
+	}
+	return f.meta
+}
+
+// gzipReader returns a reader of the uncompressed content of f.
+func (f *CompressedFileInfo) gzipReader() io.Reader {
+	gr, err := gzip.NewReader(bytes.NewReader(f.compressedContent))
+	if err != nil {
+		// This should never happen because we generate the gzip bytes such that they are always valid.
+		panic("unexpected error reading own gzip compressed bytes: " + err.Error())
+	}
+	return gr
+}
+
+// AddFileOptions configures a file added with FS.AddFile.
+type AddFileOptions struct {
+	// ModTime is the modification time of the file.
+	// If left zero, the time of the call to AddFile is used.
+	ModTime time.Time
+	
+	// Mode holds the permission bits of the file.
+	// If left zero, it defaults to 0444, so a file can't be added without any permission bits.
+	Mode os.FileMode
+	
+	// ContentType is the MIME type of the file.
+	// If left empty, it's detected from the extension or the content.
+	ContentType string
+	
+	// Source is the path the file was read from, reported in its FileMeta.
+	Source string
+	
+	// Values are arbitrary values reported in the FileMeta of the file.
+	Values map[string]string
+}
+
+// AddFile adds a file with the given content at path, creating any missing
+// parent directories. An existing file at the same path is replaced.
+func (fs *FS) AddFile(path string, content []byte, opt AddFileOptions) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	path = fs.resolveParent(path)
+	if path == "/" {
+		return &os.PathError{Op: "write", Path: path, Err: errIsDir}
+	}
+	if _, ok := fs.paths[path].(*DirInfo); ok {
+		return &os.PathError{Op: "write", Path: path, Err: errIsDir}
+	}
+	if err := fs.checkMkdirAll(pathpkg.Dir(path)); err != nil {
+		return err
+	}
+	if fs.collides(path) {
+		return &os.PathError{Op: "write", Path: path, Err: ErrNameCollision}
+	}
+	fs.version++
+	if err := fs.mkdirAll(pathpkg.Dir(path)); err != nil {
+		return err
+	}
+	f := newCompressedFileInfo(pathpkg.Base(path), content)
+	if !opt.ModTime.IsZero() {
+		f.modTime = opt.ModTime
+	}
+	f.mode = opt.Mode.Perm()
+	f.meta.Source, f.meta.Values = opt.Source, opt.Values
+	if opt.ContentType != "" {
+		f.meta.ContentType = opt.ContentType
+	}
+	return fs.put(path, f)
+}
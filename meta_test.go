@@ -0,0 +1,89 @@
+package vfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestMetaSniffedContentType(t *testing.T) {
+	fs := NewFS()
+	content := "<!DOCTYPE html>" + strings.Repeat("<p>Hello.</p>", 1000)
+	fs.Add("/", "page", []byte(content))
+	fi, err := fs.Stat("/page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := fi.(ContentTyper).ContentType(); ct != "text/html; charset=utf-8" {
+		t.Errorf("ContentType: got %q", ct)
+	}
+	if fi.(*CompressedFileInfo).meta.Hash != "" {
+		t.Error("hash computed to detect the content type")
+	}
+	if m := Meta(fi); m == nil || m.Hash != blobHash([]byte(content)) || m.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("Meta: got %+v", m)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	fs := NewFS()
+	content := []byte("body { color: red; }")
+	err := fs.AddFile("/css/site.css", content, AddFileOptions{
+		Source: "assets/site.css",
+		Values: map[string]string{"owner": "web"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fs.Stat("/css/site.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.(*CompressedFileInfo).meta.Hash != "" {
+		t.Error("hash computed before the metadata was asked for")
+	}
+	if ct := fi.(ContentTyper).ContentType(); ct != "text/css; charset=utf-8" || fi.(*CompressedFileInfo).meta.Hash != "" {
+		t.Errorf("ContentType: got %q, want it detected without computing the hash", ct)
+	}
+	m := Meta(fi)
+	if m == nil {
+		t.Fatal("Meta: got nil")
+	}
+	sum := sha256.Sum256(content)
+	if m.Hash != hex.EncodeToString(sum[:]) {
+		t.Errorf("Hash: got %q", m.Hash)
+	}
+	if m.ContentType != "text/css; charset=utf-8" {
+		t.Errorf("ContentType: got %q", m.ContentType)
+	}
+	if m.Encoding != "gzip" || m.CompressedSize != int64(len(fi.(*CompressedFileInfo).GzipBytes())) {
+		t.Errorf("Encoding, CompressedSize: got %q, %v", m.Encoding, m.CompressedSize)
+	}
+	if m.Source != "assets/site.css" || m.Values["owner"] != "web" {
+		t.Errorf("Source, Values: got %q, %v", m.Source, m.Values)
+	}
+	if err := fs.AddFile("/css", nil, AddFileOptions{}); err == nil {
+		t.Error("AddFile over directory: got nil error")
+	}
+	
+	err = fs.AddFunc("/gen.txt", func() ([]byte, error) { return []byte("produced"), nil }, AddFuncOptions{Size: -1, Source: "gen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := fs.Stat("/gen.txt"); err != nil || fi.Sys() != nil {
+		t.Errorf("Sys of unproduced file: got %v, %v, want nil", fi, err)
+	}
+	f, err := fs.Open("/gen.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+	if fi, err := fs.Stat("/gen.txt"); err != nil || Meta(fi) == nil || Meta(fi).Source != "gen" {
+		t.Errorf("Meta of produced file: got %v, %v", fi, err)
+	}
+	
+	if m := Meta(&DirInfo{name: "d"}); m != nil {
+		t.Errorf("Meta of directory: got %v, want nil", m)
+	}
+}
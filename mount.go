@@ -0,0 +1,198 @@
+package vfs
+
+import (
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MountFS is an http.FileSystem composed of other filesystems mounted at path prefixes.
+// Paths resolve to the filesystem mounted at their longest matching prefix. Directories
+// leading to mount points are synthesized when no mounted filesystem provides them,
+// and mount points always appear in the entries of their parent directory.
+type MountFS struct {
+	mu     sync.RWMutex
+	mounts map[string]http.FileSystem
+}
+
+// NewMountFS returns a MountFS with nothing mounted.
+func NewMountFS() *MountFS {
+	return &MountFS{mounts: map[string]http.FileSystem{}}
+}
+
+// Mount mounts fs at prefix, which may be at any depth.
+// It fails if something is already mounted at prefix.
+func (m *MountFS) Mount(prefix string, fs http.FileSystem) error {
+	prefix = pathpkg.Clean("/" + prefix)
+	m.mu.Lock()
+	defer func() {
+		m.mu.Unlock()
+	}()
+	
+	if m.mounts == nil {
+		m.mounts = map[string]http.FileSystem{}
+	}
+	if _, ok := m.mounts[prefix]; ok {
+		return &os.PathError{Op: "mount", Path: prefix, Err: os.ErrExist}
+	}
+	m.mounts[prefix] = fs
+	return nil
+}
+
+// Unmount removes the filesystem mounted at prefix.
+func (m *MountFS) Unmount(prefix string) error {
+	prefix = pathpkg.Clean("/" + prefix)
+	m.mu.Lock()
+	defer func() {
+		m.mu.Unlock()
+	}()
+	
+	if _, ok := m.mounts[prefix]; !ok {
+		return &os.PathError{Op: "unmount", Path: prefix, Err: os.ErrNotExist}
+	}
+	delete(m.mounts, prefix)
+	return nil
+}
+
+func (m *MountFS) Open(name string) (http.File, error) {
+	path := pathpkg.Clean("/" + name)
+	fs, inner, children := m.resolve(path)
+	
+	var f http.File
+	if fs != nil {
+		var err error
+		f, err = fs.Open(inner)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	if len(children) == 0 {
+		if f == nil {
+			return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+		}
+		return f, nil
+	}
+	
+	entries := make([]os.FileInfo, 0, len(children))
+	for _, name := range children {
+		entries = append(entries, m.mountPointInfo(pathpkg.Join(path, name)))
+	}
+	if f == nil {
+		d := &DirInfo{name: pathpkg.Base(path)}
+		return &Dir{DirInfo: d, entries: sortedEntries(entries)}, nil
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if !fi.IsDir() {
+		// Mount points below a file are unreachable.
+		return f, nil
+	}
+	return &extraEntriesDir{File: f, extra: entries}, nil
+}
+
+// resolve returns the filesystem mounted at the longest prefix of path, if any,
+// the path within it, and the names of mount points directly below path.
+func (m *MountFS) resolve(path string) (fs http.FileSystem, inner string, children []string) {
+	m.mu.RLock()
+	defer func() {
+		m.mu.RUnlock()
+	}()
+	
+	best := ""
+	seen := map[string]bool{}
+	for prefix, mfs := range m.mounts {
+		if prefix == path || prefix == "/" || strings.HasPrefix(path, prefix+"/") {
+			if fs == nil || len(prefix) > len(best) {
+				fs, best = mfs, prefix
+			}
+		}
+		rest := ""
+		switch {
+		case path == "/" && prefix != "/":
+			rest = prefix[1:]
+		case strings.HasPrefix(prefix, path+"/"):
+			rest = prefix[len(path)+1:]
+		default:
+			continue
+		}
+		child := strings.SplitN(rest, "/", 2)[0]
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	if fs != nil {
+		inner = pathpkg.Clean("/" + strings.TrimPrefix(path, best))
+	}
+	return fs, inner, children
+}
+
+// mountPointInfo returns the os.FileInfo of a directory that is, or leads to, a mount point.
+func (m *MountFS) mountPointInfo(path string) os.FileInfo {
+	m.mu.RLock()
+	fs, ok := m.mounts[path]
+	m.mu.RUnlock()
+	if ok {
+		if fi, err := stat(fs, "/"); err == nil {
+			return withName(fi, pathpkg.Base(path))
+		}
+	}
+	return &DirInfo{name: pathpkg.Base(path)}
+}
+
+// renamedFileInfo is an os.FileInfo reporting a different name.
+type renamedFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (fi renamedFileInfo) Name() string { return fi.name }
+
+func sortedEntries(entries []os.FileInfo) []os.FileInfo {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries
+}
+
+// extraEntriesDir is a directory with additional entries, which
+// take precedence over the directory's own entries with the same name.
+type extraEntriesDir struct {
+	http.File
+	extra   []os.FileInfo
+	entries []os.FileInfo // Merged entries, nil until first Readdir.
+	pos     int
+}
+
+func (d *extraEntriesDir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.pos = 0
+	}
+	return d.File.Seek(offset, whence)
+}
+
+func (d *extraEntriesDir) Readdir(count int) ([]os.FileInfo, error) {
+	if d.entries == nil {
+		fis, err := d.File.Readdir(0)
+		if err != nil {
+			return nil, err
+		}
+		seen := map[string]bool{}
+		d.entries = append([]os.FileInfo{}, d.extra...)
+		for _, fi := range d.extra {
+			seen[fi.Name()] = true
+		}
+		for _, fi := range fis {
+			if !seen[fi.Name()] {
+				d.entries = append(d.entries, fi)
+			}
+		}
+		sortedEntries(d.entries)
+	}
+	return readdirPage(d.entries, &d.pos, count)
+}
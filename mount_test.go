@@ -0,0 +1,88 @@
+package vfs
+
+import (
+	"os"
+	"strings"
+	"testing"
+	
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestMountFS(t *testing.T) {
+	site := httpfs.New(mapfs.New(map[string]string{
+		"index.html":        "index",
+		"static/style.css":  "style",
+		"static/vendor.txt": "shadowed",
+	}))
+	vendor := NewFS()
+	vendor.Add("/", "jquery.js", []byte("jquery"))
+	vendor.Add("/lib", "a.js", []byte("a"))
+	docs := NewFS()
+	docs.Add("/", "README", []byte("readme"))
+	
+	m := NewMountFS()
+	if err := m.Mount("/", site); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Mount("/static/vendor", vendor); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Mount("api/v1/docs", docs); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Mount("/static/vendor/", docs); !os.IsExist(err) {
+		t.Errorf("Mount twice: got %v, want exist error", err)
+	}
+	
+	for path, want := range map[string]string{
+		"/index.html":              "index",
+		"/static/style.css":        "style",
+		"/static/vendor/jquery.js": "jquery",
+		"/static/vendor/lib/a.js":  "a",
+		"/api/v1/docs/README":      "readme",
+	} {
+		if got := readFileT(t, m, path); got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+	for dir, want := range map[string]string{
+		"/":              "api,index.html,static",
+		"/api":           "v1",
+		"/api/v1":        "docs",
+		"/static":        "style.css,vendor,vendor.txt",
+		"/static/vendor": "jquery.js,lib",
+	} {
+		if got := readDirNamesT(t, m, dir); got != want {
+			t.Errorf("Readdir(%q): got %q, want %q", dir, got, want)
+		}
+	}
+	
+	var walked []string
+	err := Walk(m, "/", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			walked = append(walked, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "/api/v1/docs/README,/index.html,/static/style.css,/static/vendor/jquery.js,/static/vendor/lib/a.js,/static/vendor.txt"
+	if got := strings.Join(walked, ","); got != want {
+		t.Errorf("Walk: got %q, want %q", got, want)
+	}
+	
+	if err := m.Unmount("/api/v1/docs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Open("/api"); !os.IsNotExist(err) {
+		t.Errorf("Open after Unmount: got %v, want not exist error", err)
+	}
+	if err := m.Unmount("/api/v1/docs"); !os.IsNotExist(err) {
+		t.Errorf("Unmount twice: got %v, want not exist error", err)
+	}
+}
@@ -0,0 +1,107 @@
+package vfs
+
+import (
+	"net/http"
+	"os"
+	pathpkg "path"
+	
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// FSOptions configures how an FS created with NewFSWithOptions, or a filesystem
+// returned by MatchNames, matches paths to the names of files and directories.
+// Files and directories keep their original names in FileInfo.Name and Readdir.
+type FSOptions struct {
+	// CaseInsensitive matches names regardless of case, using Unicode case folding.
+	CaseInsensitive bool
+	
+	// NormalizeUnicode matches names after normalizing them to Unicode NFC,
+	// so that names written in decomposed form, as on macOS, match their composed form.
+	NormalizeUnicode bool
+}
+
+// keyFunc returns a function mapping paths to the keys they're matched by,
+// or nil if paths are matched exactly.
+func (opt FSOptions) keyFunc() func(string) string {
+	if !opt.CaseInsensitive && !opt.NormalizeUnicode {
+		return nil
+	}
+	return func(path string) string {
+		if opt.NormalizeUnicode {
+			path = norm.NFC.String(path)
+		}
+		if opt.CaseInsensitive {
+			// A Caser is not safe for concurrent use.
+			path = cases.Fold().String(path)
+			if opt.NormalizeUnicode {
+				// Folding can decompose characters, such as U+0390.
+				path = norm.NFC.String(path)
+			}
+		}
+		return path
+	}
+}
+
+// MatchNames returns an http.FileSystem that opens the files of fs by matching
+// paths as configured by opt. An exact match is preferred, otherwise the path is
+// looked up one directory at a time. If several names in a directory match,
+// opening fails with ErrNameCollision.
+func MatchNames(fs http.FileSystem, opt FSOptions) http.FileSystem {
+	key := opt.keyFunc()
+	if key == nil {
+		return fs
+	}
+	return &matchNamesFS{fs: fs, key: key}
+}
+
+type matchNamesFS struct {
+	fs  http.FileSystem
+	key func(string) string
+}
+
+func (m *matchNamesFS) Open(name string) (http.File, error) {
+	path := pathpkg.Clean("/" + name)
+	f, err := m.fs.Open(path)
+	if err == nil || !os.IsNotExist(err) {
+		return f, err
+	}
+	resolved, err := m.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return m.fs.Open(resolved)
+}
+
+// resolve returns the path of the file or directory in fs matching path.
+func (m *matchNamesFS) resolve(path string) (string, error) {
+	if path == "/" {
+		return path, nil
+	}
+	dir, err := m.resolve(pathpkg.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	name := pathpkg.Base(path)
+	if exists(m.fs, pathpkg.Join(dir, name)) {
+		return pathpkg.Join(dir, name), nil
+	}
+	fis, err := readDir(m.fs, dir)
+	if err != nil {
+		return "", &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	match := ""
+	for _, fi := range fis {
+		if m.key(fi.Name()) != m.key(name) {
+			continue
+		}
+		if match != "" {
+			return "", &os.PathError{Op: "open", Path: path, Err: ErrNameCollision}
+		}
+		match = fi.Name()
+	}
+	if match == "" {
+		return "", &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return pathpkg.Join(dir, match), nil
+}
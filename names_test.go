@@ -0,0 +1,107 @@
+package vfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+	
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestFSMatchNames(t *testing.T) {
+	nfd := norm.NFD.String("Café")
+	fs := NewFSWithOptions(FSOptions{CaseInsensitive: true, NormalizeUnicode: true})
+	fs.Add("/Assets/"+nfd, "Logo.PNG", []byte("logo"))
+	
+	if got := readFileT(t, fs, "/assets/café/logo.png"); got != "logo" {
+		t.Errorf("got %q", got)
+	}
+	fi, err := fs.Stat("/ASSETS/CAFÉ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != nfd {
+		t.Errorf("got name %q, want original name %q", fi.Name(), nfd)
+	}
+	if got := readDirNamesT(t, fs, "/assets"); got != nfd {
+		t.Errorf("Readdir: got %q", got)
+	}
+	
+	// Writing to an existing name replaces it, writing to a matching
+	// different name collides.
+	if err := fs.WriteFile("/assets/café/Logo.PNG", []byte("new logo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("/assets/café/logo.png", []byte("other")); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("WriteFile: got %v, want collision", err)
+	}
+	if err := fs.Mkdir("/assets/CAFÉ"); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("Mkdir: got %v, want collision", err)
+	}
+	if err := fs.MkdirAll("/assets/CAFÉ/img"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDirNamesT(t, fs, "/Assets/"+nfd); got != "Logo.PNG,img" {
+		t.Errorf("Readdir: got %q", got)
+	}
+	
+	staging := NewFS()
+	staging.Add("/", "README", nil)
+	staging.Add("/", "readme", nil)
+	if err := fs.Replace("/docs", staging); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("Replace: got %v, want collision", err)
+	}
+	
+	// Renaming to a name that only differs in case changes the name.
+	if err := fs.Rename("/assets/café/logo.png", "/assets/café/logo.png"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDirNamesT(t, fs, "/assets/café"); got != "img,logo.png" {
+		t.Errorf("Readdir after rename: got %q", got)
+	}
+	if err := fs.RemoveAll("/ASSETS"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readDirNamesT(t, fs, "/"); got != "" {
+		t.Errorf("Readdir after RemoveAll: got %q", got)
+	}
+}
+
+func TestFSOptionsKey(t *testing.T) {
+	key := FSOptions{CaseInsensitive: true, NormalizeUnicode: true}.keyFunc()
+	for _, names := range [][2]string{
+		{"Café", norm.NFD.String("CAFÉ")},
+		// U+0390 folds to U+03B9 U+0308 U+0301, which isn't NFC, while
+		// U+03AA U+0301 folds to U+03CA U+0301, canonically equivalent to it.
+		{"\u0390", "\u03aa\u0301"},
+		// U+01F0 folds to j U+030C, which isn't NFC.
+		{"\u01f0", "J\u030c"},
+	} {
+		if k0, k1 := key(names[0]), key(names[1]); k0 != k1 {
+			t.Errorf("keys of %+q and %+q: got %+q and %+q, want equal", names[0], names[1], k0, k1)
+		}
+	}
+}
+
+func TestMatchNames(t *testing.T) {
+	fs := MatchNames(httpfs.New(mapfs.New(map[string]string{
+		"Docs/" + norm.NFD.String("Résumé.txt"): "resume",
+		"both/A.txt": "upper",
+		"both/a.txt": "lower",
+	})), FSOptions{CaseInsensitive: true, NormalizeUnicode: true})
+	
+	if got := readFileT(t, fs, "/docs/résumé.TXT"); got != "resume" {
+		t.Errorf("got %q", got)
+	}
+	if got := readFileT(t, fs, "/both/a.txt"); got != "lower" {
+		t.Errorf("got %q, want exact match", got)
+	}
+	if _, err := fs.Open("/BOTH/a.TXT"); !errors.Is(err, ErrNameCollision) {
+		t.Errorf("got %v, want collision", err)
+	}
+	if _, err := fs.Open("/docs/missing"); !os.IsNotExist(err) {
+		t.Errorf("got %v, want not exist error", err)
+	}
+}
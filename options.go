@@ -1,8 +1,16 @@
 package vfs
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
+	"io"
+	"os"
+	pathpkg "path"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Options for vfsgen code generation.
@@ -26,6 +34,283 @@ type Options struct {
 	// VariableComment is the comment of the http.FileSystem variable in the generated code.
 	// If left empty, it defaults to "{{.VariableName}} statically implements the virtual filesystem provided to vfsgen.".
 	VariableComment string
+	
+	// Include is a list of gitignore style patterns, as described by FilterRules.Exclude.
+	// If set, only the files matching it, or inside a directory matching it, are
+	// included, and directories left without included files are left out.
+	// Empty directories of the input are kept.
+	Include []string
+	
+	// Exclude is a list of gitignore style patterns of the files and directories left
+	// out, as described by FilterRules.Exclude. The patterns of a .vfsignore file at
+	// the root of the input filesystem are added after them, and it's left out too.
+	Exclude []string
+	
+	// Skipped, if set, is called with the sorted paths of the files and directories
+	// left out because of Include, Exclude or .vfsignore, once generation succeeded.
+	// The .vfsignore file itself isn't reported.
+	Skipped func(paths []string)
+	
+	// Mode, if set, is called with the path and mode of each input file and directory,
+	// and returns the permission bits recorded for it. NormalizeMode can be used
+	// for builds that must not depend on the permissions of the input files.
+	// If left nil, their actual permission bits are recorded.
+	Mode func(path string, mode os.FileMode) os.FileMode
+	
+	// ContentTypes are rules setting the MIME type of input files, the first matching
+	// rule applies. The MIME type of other files is detected from their extension,
+	// or by sniffing their content if the extension is unknown.
+	ContentTypes []ContentTypeRule
+	
+	// MetaValues, if set, is called with the path of each input file, and returns
+	// arbitrary values reported in the FileMeta of the generated file.
+	MetaValues func(path string) map[string]string
+	
+	// Reproducible makes the generated code independent of the mod times and
+	// permissions of the input files, so that generating it from different
+	// checkouts of the same files gives identical output. The mod time of all
+	// files and directories is then FixedModTime. As checkouts get permissions
+	// that depend on the umask, Mode defaults to NormalizeMode rather than
+	// recording the actual permission bits; set Mode to override it.
+	Reproducible bool
+	
+	// FixedModTime is the mod time of all files and directories in reproducible mode.
+	// If left zero, it's read from the SOURCE_DATE_EPOCH environment variable,
+	// as a number of seconds since the Unix epoch, or left zero if it's not set.
+	FixedModTime time.Time
+	
+	// ModTime, if set, is called with the path and info of each input file and
+	// directory, and returns the mod time recorded for it. It takes precedence
+	// over reproducible mode. GitModTime returns an implementation using git history.
+	ModTime func(path string, fi os.FileInfo) (time.Time, error)
+	
+	// Compression configures how files are compressed.
+	Compression CompressionOptions
+	
+	// Concurrency is the maximum number of files compressed concurrently.
+	// The output doesn't depend on it.
+	// If left zero, it defaults to runtime.GOMAXPROCS(0).
+	Concurrency int
+	
+	// Symlinks is how symbolic links in the input filesystem are handled.
+	// They're recognized by the os.ModeSymlink mode bit of directory entries.
+	// If left zero, they're followed.
+	Symlinks SymlinkPolicy
+}
+
+// modTime returns the mod time to record for the input file or directory at path.
+func (opt *Options) modTime(path string, fi os.FileInfo) (time.Time, error) {
+	switch {
+	case opt.ModTime != nil:
+		t, err := opt.ModTime(path, fi)
+		return t.UTC(), err
+	case opt.Reproducible:
+		return opt.FixedModTime.UTC(), nil
+	default:
+		return fi.ModTime().UTC(), nil
+	}
+}
+
+// mode returns the permission bits to record for the input file or directory at path.
+func (opt *Options) mode(path string, mode os.FileMode) os.FileMode {
+	if opt.Mode != nil {
+		mode = opt.Mode(path, mode)
+	}
+	return mode.Perm()
+}
+
+// NormalizeMode is an Options.Mode function recording directories as 0755, and files
+// as 0755 if they're executable by anyone, 0644 otherwise.
+func NormalizeMode(path string, mode os.FileMode) os.FileMode {
+	if mode.IsDir() || mode&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
+// CompressionOptions configures how Generate compresses files. Files are stored
+// uncompressed when compressing them doesn't save enough space.
+type CompressionOptions struct {
+	// Level points to the gzip compression level, from gzip.HuffmanOnly to
+	// gzip.BestCompression, so that gzip.NoCompression can be chosen.
+	// If left nil, it defaults to gzip.BestCompression.
+	Level *int
+	
+	// MinSavings is the minimum number of bytes compressing a file must save.
+	// If left zero, any saving is enough.
+	MinSavings int64
+	
+	// MinSavingsPercent is the minimum percentage of its size compressing a file must save.
+	MinSavingsPercent float64
+	
+	// ShouldCompress, if set, is called for each file that isn't skipped because of
+	// its extension or content, and the file is stored uncompressed when it returns false.
+	ShouldCompress func(path string, fi os.FileInfo) bool
+	
+	// SkipExtensions are the extensions, such as ".png", of files stored uncompressed
+	// without trying to compress them. If nil, DefaultSkipExtensions is used.
+	SkipExtensions []string
+	
+	// SkipMagic are the signatures of files stored uncompressed without trying to
+	// compress them. If nil, DefaultSkipMagic is used.
+	SkipMagic []Magic
+}
+
+// Magic is the signature of a file format, found at the start of the content of its files.
+type Magic struct {
+	// Bytes are the leading bytes of the content.
+	Bytes []byte
+	
+	// Mask, if set, has the bits of Bytes to compare, so that the values of
+	// some bytes don't matter. It must have the same length as Bytes.
+	Mask []byte
+}
+
+// match reports whether head starts with the signature.
+func (m Magic) match(head []byte) bool {
+	if len(m.Bytes) == 0 || len(head) < len(m.Bytes) {
+		return false
+	}
+	if m.Mask == nil {
+		return bytes.HasPrefix(head, m.Bytes)
+	}
+	for i, b := range m.Bytes {
+		if head[i]&m.Mask[i] != b&m.Mask[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultSkipExtensions are the extensions of common already compressed formats.
+var DefaultSkipExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".webp", ".avif", ".ico",
+	".woff", ".woff2",
+	".zip", ".gz", ".tgz", ".bz2", ".xz", ".zst", ".br", ".7z",
+	".mp3", ".mp4", ".ogg", ".webm",
+}
+
+// DefaultSkipMagic are the signatures of common already compressed formats.
+var DefaultSkipMagic = []Magic{
+	{Bytes: []byte("\x89PNG\r\n\x1a\n")}, // PNG.
+	{Bytes: []byte("\xff\xd8\xff")},      // JPEG.
+	{Bytes: []byte("GIF8")},              // GIF.
+	{Bytes: []byte("wOFF")},              // WOFF.
+	{Bytes: []byte("wOF2")},              // WOFF2.
+	{Bytes: []byte("PK\x03\x04")},        // Zip.
+	{Bytes: []byte("\x1f\x8b")},          // Gzip.
+	// WebP, whatever the RIFF chunk size.
+	{Bytes: []byte("RIFF\x00\x00\x00\x00WEBP"), Mask: []byte("\xff\xff\xff\xff\x00\x00\x00\x00\xff\xff\xff\xff")},
+}
+
+func (c *CompressionOptions) level() int {
+	if c.Level == nil {
+		return gzip.BestCompression
+	}
+	return *c.Level
+}
+
+// validate reports invalid compression options.
+func (c *CompressionOptions) validate() error {
+	if l := c.level(); l < gzip.HuffmanOnly || l > gzip.BestCompression {
+		return fmt.Errorf("invalid compression level %d", l)
+	}
+	for _, m := range c.SkipMagic {
+		if m.Mask != nil && len(m.Mask) != len(m.Bytes) {
+			return fmt.Errorf("magic %q has a mask of %d bytes, want %d", m.Bytes, len(m.Mask), len(m.Bytes))
+		}
+	}
+	return nil
+}
+
+// compress reports whether to try compressing the file at path, reading the start of
+// its content from r if needed. r is left at its start.
+func (c *CompressionOptions) compress(path string, fi os.FileInfo, r io.ReadSeeker) (bool, error) {
+	exts := c.SkipExtensions
+	if exts == nil {
+		exts = DefaultSkipExtensions
+	}
+	ext := pathpkg.Ext(path)
+	for _, e := range exts {
+		if strings.EqualFold(e, ext) {
+			return false, nil
+		}
+	}
+	magic := c.SkipMagic
+	if magic == nil {
+		magic = DefaultSkipMagic
+	}
+	n := 0
+	for _, m := range magic {
+		if len(m.Bytes) > n {
+			n = len(m.Bytes)
+		}
+	}
+	if n > 0 {
+		head, err := readHead(r, n)
+		if err != nil {
+			return false, err
+		}
+		for _, m := range magic {
+			if m.match(head) {
+				return false, nil
+			}
+		}
+	}
+	return c.ShouldCompress == nil || c.ShouldCompress(path, fi), nil
+}
+
+// worth reports whether compressing size bytes into compressedSize bytes saves enough.
+func (c *CompressionOptions) worth(size, compressedSize int64) bool {
+	savings := size - compressedSize
+	if savings <= 0 || savings < c.MinSavings {
+		return false
+	}
+	return float64(savings)*100 >= c.MinSavingsPercent*float64(size)
+}
+
+// SymlinkPolicy is how Generate handles symbolic links in its input.
+type SymlinkPolicy int
+
+// Symbolic link policies.
+const (
+	// FollowSymlinks includes what symbolic links point to in their place.
+	// Links that form a loop make Generate fail with ErrSymlinkLoop.
+	FollowSymlinks SymlinkPolicy = iota
+	
+	// PreserveSymlinks includes symbolic links as links, which the generated
+	// filesystem follows on Open and reports through Readlink.
+	// The input filesystem must implement Readlinker.
+	PreserveSymlinks
+	
+	// RejectSymlinks makes Generate fail if the input contains symbolic links.
+	RejectSymlinks
+)
+
+func (opt *Options) concurrency() int {
+	if opt.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return opt.Concurrency
+}
+
+// fillReproducible sets the defaults of reproducible mode: FixedModTime from
+// SOURCE_DATE_EPOCH, and Mode to NormalizeMode, as documented by Options.Reproducible.
+func (opt *Options) fillReproducible() error {
+	if !opt.Reproducible {
+		return nil
+	}
+	if s := os.Getenv("SOURCE_DATE_EPOCH"); opt.FixedModTime.IsZero() && s != "" {
+		sec, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %v", s, err)
+		}
+		opt.FixedModTime = time.Unix(sec, 0)
+	}
+	if opt.Mode == nil {
+		opt.Mode = NormalizeMode
+	}
+	return nil
 }
 
 // fillMissing sets default values for mandatory options that are left empty.
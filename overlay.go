@@ -0,0 +1,193 @@
+package vfs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strings"
+)
+
+const (
+	// WhiteoutPrefix is the name prefix of whiteout markers. In an Overlay, a file named
+	// WhiteoutPrefix+name hides name in the same directory of all lower layers.
+	WhiteoutPrefix = ".wh."
+	
+	// OpaqueWhiteout is the name of a marker that hides the contents
+	// of the directory it's in from all lower layers of an Overlay.
+	OpaqueWhiteout = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// Overlay returns an http.FileSystem that layers the given filesystems on top of each other.
+// A path is looked up in each layer in order, and the first layer that has it wins.
+// Directories present in several layers are merged, with their entries deduplicated and sorted.
+// Whiteout markers (see WhiteoutPrefix and OpaqueWhiteout) let an upper layer hide
+// files and directories of lower layers. The markers themselves are never visible.
+func Overlay(layers ...http.FileSystem) http.FileSystem {
+	return &overlayFS{layers: layers}
+}
+
+// Whiteout adds a marker to fs that hides path in the layers below fs when used in an Overlay.
+func Whiteout(fs *FS, path string) error {
+	path = pathpkg.Clean("/" + path)
+	if path == "/" {
+		return &os.PathError{Op: "whiteout", Path: path, Err: os.ErrInvalid}
+	}
+	if err := fs.MkdirAll(pathpkg.Dir(path)); err != nil {
+		return err
+	}
+	return fs.WriteFile(pathpkg.Join(pathpkg.Dir(path), WhiteoutPrefix+pathpkg.Base(path)), nil)
+}
+
+type overlayFS struct {
+	layers []http.FileSystem
+}
+
+func (o *overlayFS) Open(name string) (http.File, error) {
+	path := pathpkg.Clean("/" + name)
+	if strings.HasPrefix(pathpkg.Base(path), WhiteoutPrefix) {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	
+	var dirs []http.File
+	for _, layer := range o.layers {
+		f, err := layer.Open(path)
+		if err == nil {
+			fi, err := f.Stat()
+			if err != nil {
+				_ = f.Close()
+				closeAll(dirs)
+				return nil, err
+			}
+			if !fi.IsDir() {
+				if len(dirs) == 0 {
+					return f, nil
+				}
+				// A directory in an upper layer hides files in lower ones.
+				_ = f.Close()
+				break
+			}
+			dirs = append(dirs, f)
+		} else if !os.IsNotExist(err) {
+			closeAll(dirs)
+			return nil, err
+		}
+		if hidesLower(layer, path) {
+			break
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return &overlayDir{File: dirs[0], dirs: dirs}, nil
+}
+
+// hidesLower reports whether layer hides path from the layers below it. That is the case
+// when path or one of its parents is whited out, one of its parents is not a directory,
+// or path or one of its parents is an opaque directory.
+func hidesLower(layer http.FileSystem, path string) bool {
+	for p := path; ; p = pathpkg.Dir(p) {
+		if exists(layer, pathpkg.Join(p, OpaqueWhiteout)) {
+			return true
+		}
+		if p == "/" {
+			return false
+		}
+		if exists(layer, pathpkg.Join(pathpkg.Dir(p), WhiteoutPrefix+pathpkg.Base(p))) {
+			return true
+		}
+		if p != path {
+			if fi, err := stat(layer, p); err == nil && !fi.IsDir() {
+				return true
+			}
+		}
+	}
+}
+
+func exists(fs http.FileSystem, path string) bool {
+	_, err := stat(fs, path)
+	return err == nil
+}
+
+func closeAll(files []http.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
+
+// overlayDir is a directory merged from several layers, topmost first.
+// Stat reports the topmost directory.
+type overlayDir struct {
+	http.File
+	dirs    []http.File
+	entries []os.FileInfo // Merged entries, nil until first Readdir.
+	pos     int
+}
+
+func (d *overlayDir) Read([]byte) (int, error) {
+	fi, _ := d.Stat()
+	return 0, fmt.Errorf("cannot Read from directory %s", fi.Name())
+}
+
+func (d *overlayDir) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		d.pos = 0
+		return 0, nil
+	}
+	fi, _ := d.Stat()
+	return 0, fmt.Errorf("unsupported Seek in directory %s", fi.Name())
+}
+
+func (d *overlayDir) Readdir(count int) ([]os.FileInfo, error) {
+	if d.entries == nil {
+		if err := d.merge(); err != nil {
+			return nil, err
+		}
+	}
+	return readdirPage(d.entries, &d.pos, count)
+}
+
+func (d *overlayDir) merge() error {
+	seen := map[string]bool{}
+	d.entries = []os.FileInfo{}
+	for _, dir := range d.dirs {
+		fis, err := dir.Readdir(0)
+		if err != nil {
+			return err
+		}
+		var whiteouts []string
+		opaque := false
+		for _, fi := range fis {
+			name := fi.Name()
+			switch {
+			case name == OpaqueWhiteout:
+				opaque = true
+			case strings.HasPrefix(name, WhiteoutPrefix):
+				whiteouts = append(whiteouts, strings.TrimPrefix(name, WhiteoutPrefix))
+			case !seen[name]:
+				seen[name] = true
+				d.entries = append(d.entries, fi)
+			}
+		}
+		// Whiteouts only apply to lower layers.
+		for _, name := range whiteouts {
+			seen[name] = true
+		}
+		if opaque {
+			break
+		}
+	}
+	sortedEntries(d.entries)
+	return nil
+}
+
+func (d *overlayDir) Close() error {
+	var err error
+	for _, f := range d.dirs {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
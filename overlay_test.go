@@ -0,0 +1,55 @@
+package vfs
+
+import (
+	"os"
+	"testing"
+	
+	"golang.org/x/tools/godoc/vfs/httpfs"
+	"golang.org/x/tools/godoc/vfs/mapfs"
+)
+
+func TestOverlay(t *testing.T) {
+	generated := httpfs.New(mapfs.New(map[string]string{
+		"index.html":       "generated index",
+		"logo.png":         "generated logo",
+		"old/page.html":    "old page",
+		"themes/a/x.css":   "a",
+		"themes/b/y.css":   "b",
+		"vendor/lib.js":    "lib",
+		"vendor/extra.js":  "extra",
+		"templates/t.tmpl": "template",
+	}))
+	upper := NewFS()
+	upper.Add("/", "index.html", []byte("overridden index"))
+	upper.Add("/themes/c", "z.css", []byte("c"))
+	upper.Add("/vendor", "lib.js", []byte("new lib"))
+	upper.Add("/vendor", OpaqueWhiteout, nil)
+	if err := Whiteout(upper, "/logo.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Whiteout(upper, "/old"); err != nil {
+		t.Fatal(err)
+	}
+	fs := Overlay(upper, generated)
+	
+	if got := readFileT(t, fs, "/index.html"); got != "overridden index" {
+		t.Errorf("got %q, want upper layer content", got)
+	}
+	if got := readFileT(t, fs, "/templates/t.tmpl"); got != "template" {
+		t.Errorf("got %q, want lower layer content", got)
+	}
+	for _, name := range []string{"/logo.png", "/old/page.html", "/vendor/extra.js", "/.wh.logo.png"} {
+		if _, err := fs.Open(name); !os.IsNotExist(err) {
+			t.Errorf("Open(%q): got %v, want not exist error", name, err)
+		}
+	}
+	for dir, want := range map[string]string{
+		"/":       "index.html,templates,themes,vendor",
+		"/themes": "a,b,c",
+		"/vendor": "lib.js",
+	} {
+		if got := readDirNamesT(t, fs, dir); got != want {
+			t.Errorf("Readdir(%q): got %q, want %q", dir, got, want)
+		}
+	}
+}
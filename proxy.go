@@ -1,13 +1,20 @@
 package vfs
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	pathpkg "path"
 	"path/filepath"
 	"strings"
+	"syscall"
 )
 
+// Proxy returns an http.Dir for dir, resolved relative to the working directory.
+// It panics if dir can't be resolved or is not a directory.
+// See NewProxy for a filesystem that reports errors and confines access to dir.
 func Proxy(dir string) http.FileSystem {
 	var err error
 	defer func() {
@@ -36,3 +43,254 @@ func Proxy(dir string) http.FileSystem {
 	}
 	return http.Dir(p)
 }
+
+// ProxyOptions configures a filesystem returned by NewProxy.
+type ProxyOptions struct {
+	// HideDotfiles hides files and directories whose name starts with a dot,
+	// such as .git or .env.
+	HideDotfiles bool
+	
+	// Hide is a list of path.Match patterns. Files and directories whose name,
+	// or slash separated path starting with "/", matches any of them are hidden.
+	Hide []string
+}
+
+// ProxyError describes why NewProxy failed, or why a file of the proxied
+// directory couldn't be accessed for a reason other than not existing.
+type ProxyError struct {
+	Dir string
+	Err error
+}
+
+func (e *ProxyError) Error() string { return "proxy " + e.Dir + ": " + e.Err.Error() }
+func (e *ProxyError) Unwrap() error { return e.Err }
+
+// ErrOutsideRoot is returned when opening a path that escapes the proxy root,
+// either through ".." elements or a symbolic link, and when reading a symbolic
+// link of a Sub or Rewrite filesystem whose target is out of it.
+var ErrOutsideRoot = fmt.Errorf("path escapes proxy root: %w", os.ErrPermission)
+
+// NewProxy returns an http.FileSystem serving the directory tree at dir from disk.
+// Relative dir is resolved against the working directory once, when called.
+// Unlike http.Dir, it refuses to follow symbolic links out of dir and can hide files.
+// Misconfiguration is reported with a *ProxyError.
+//
+// Opened files are checked to be the ones that were resolved inside dir. Replacing
+// a parent directory of a file by a symbolic link while it's being opened is not
+// detected, so dir must not be writable by untrusted users.
+func NewProxy(dir string, opt ProxyOptions) (http.FileSystem, error) {
+	for _, pattern := range opt.Hide {
+		if _, err := pathpkg.Match(pattern, ""); err != nil {
+			return nil, &ProxyError{Dir: dir, Err: fmt.Errorf("invalid hide pattern %q: %w", pattern, err)}
+		}
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, &ProxyError{Dir: dir, Err: err}
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, &ProxyError{Dir: dir, Err: err}
+	}
+	fi, err := os.Stat(root)
+	if err != nil {
+		return nil, &ProxyError{Dir: dir, Err: err}
+	}
+	if !fi.IsDir() {
+		return nil, &ProxyError{Dir: dir, Err: ErrNotDir}
+	}
+	return &proxyFS{root: root, opt: opt}, nil
+}
+
+type proxyFS struct {
+	root string // Absolute, with symbolic links resolved.
+	opt  ProxyOptions
+}
+
+func (p *proxyFS) Open(name string) (http.File, error) {
+	path, err := p.clean("open", name)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := p.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.open(path, resolved)
+}
+
+// open opens the file at path, resolved on disk at resolved. It fails if the
+// file at resolved is no longer the one that was resolved.
+func (p *proxyFS) open(path, resolved string) (http.File, error) {
+	f, err := os.Open(resolved)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: errors.Unwrap(err)}
+	}
+	// The file may have been replaced by a symbolic link since it was resolved.
+	fi, err := f.Stat()
+	if err == nil {
+		var lfi os.FileInfo
+		lfi, err = os.Lstat(resolved)
+		if err == nil && !os.SameFile(fi, lfi) {
+			err = ErrOutsideRoot
+		}
+	}
+	if err != nil {
+		_ = f.Close()
+		if !errors.Is(err, ErrOutsideRoot) {
+			err = errors.Unwrap(err)
+		}
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	return &proxyFile{File: f, fs: p, path: path}, nil
+}
+
+// Readlink returns the target of the symbolic link at name. Absolute targets
+// are returned relative to the root, targets outside of it are an ErrOutsideRoot error.
+func (p *proxyFS) Readlink(name string) (string, error) {
+	path, err := p.clean("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.resolve(path); err != nil {
+		return "", err
+	}
+	dir, err := p.resolve(pathpkg.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	target, err := os.Readlink(filepath.Join(dir, pathpkg.Base(path)))
+	if err != nil {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: errors.Unwrap(err)}
+	}
+	if !filepath.IsAbs(target) {
+		return filepath.ToSlash(target), nil
+	}
+	rel, ok := p.rel(target)
+	if !ok {
+		// The target may be inside the root through a symbolic link, as the root has them resolved.
+		if resolved, err := filepath.EvalSymlinks(target); err == nil {
+			rel, ok = p.rel(resolved)
+		}
+	}
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: ErrOutsideRoot}
+	}
+	return rel, nil
+}
+
+// rel returns the slash separated path of the absolute location on disk
+// relative to the root, and whether it's inside the root.
+func (p *proxyFS) rel(location string) (string, bool) {
+	rel, err := filepath.Rel(p.root, location)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return pathpkg.Clean("/" + filepath.ToSlash(rel)), true
+}
+
+// clean validates name and returns it as a clean path. It fails if name
+// contains ".." elements, or is hidden.
+func (p *proxyFS) clean(op, name string) (string, error) {
+	if strings.Contains(name, "\x00") || filepath.Separator != '/' && strings.ContainsRune(name, filepath.Separator) {
+		return "", &os.PathError{Op: op, Path: name, Err: os.ErrInvalid}
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return "", &os.PathError{Op: op, Path: name, Err: ErrOutsideRoot}
+		}
+	}
+	path := pathpkg.Clean("/" + name)
+	if p.hidden(path) {
+		return "", &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	return path, nil
+}
+
+// resolve returns the location of path on disk, with symbolic links resolved.
+// It fails if the location is outside the root. Missing files are reported as
+// os.ErrNotExist, other failures, such as a symbolic link loop, as a *ProxyError.
+func (p *proxyFS) resolve(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(p.root, filepath.FromSlash(path)))
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return "", &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	} else if err != nil {
+		return "", &os.PathError{Op: "open", Path: path, Err: &ProxyError{Dir: p.root, Err: err}}
+	}
+	if resolved != p.root && !strings.HasPrefix(resolved, p.root+string(filepath.Separator)) {
+		return "", &os.PathError{Op: "open", Path: path, Err: ErrOutsideRoot}
+	}
+	return resolved, nil
+}
+
+// hidden reports whether path or any of its parents is hidden.
+func (p *proxyFS) hidden(path string) bool {
+	for path != "/" {
+		name := pathpkg.Base(path)
+		if p.opt.HideDotfiles && strings.HasPrefix(name, ".") {
+			return true
+		}
+		for _, pattern := range p.opt.Hide {
+			if ok, _ := pathpkg.Match(pattern, name); ok {
+				return true
+			}
+			if ok, _ := pathpkg.Match(pattern, path); ok {
+				return true
+			}
+		}
+		path = pathpkg.Dir(path)
+	}
+	return false
+}
+
+// proxyFile is a file opened by proxyFS. Stat reports the name it was opened with,
+// rather than the one of a symbolic link target. Readdir omits hidden entries
+// and symbolic links that escape the root.
+type proxyFile struct {
+	*os.File
+	fs   *proxyFS
+	path string
+}
+
+func (f *proxyFile) Stat() (os.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil || f.path == "/" {
+		return fi, err
+	}
+	return withName(fi, pathpkg.Base(f.path)), nil
+}
+
+func (f *proxyFile) Readdir(count int) ([]os.FileInfo, error) {
+	var fis []os.FileInfo
+	for {
+		n := count
+		if count > 0 {
+			n = count - len(fis)
+		}
+		batch, err := f.File.Readdir(n)
+		for _, fi := range batch {
+			if f.visible(fi) {
+				fis = append(fis, fi)
+			}
+		}
+		if count <= 0 || len(fis) == count || err != nil {
+			if err == io.EOF && len(fis) > 0 {
+				err = nil
+			}
+			return fis, err
+		}
+	}
+}
+
+func (f *proxyFile) visible(fi os.FileInfo) bool {
+	path := pathpkg.Join(f.path, fi.Name())
+	if f.fs.hidden(path) {
+		return false
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		if _, err := f.fs.resolve(path); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package vfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewProxy(t *testing.T) {
+	tempDir := t.TempDir()
+	root := filepath.Join(tempDir, "assets")
+	for name, content := range map[string]string{
+		"assets/index.html":     "index",
+		"assets/.git/config":    "secret",
+		"assets/drafts/a.psd":   "draft",
+		"assets/vendor/lib.js":  "lib",
+		"outside/passwords.txt": "secret",
+	} {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(tempDir, "outside"), filepath.Join(root, "escape")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	if err := os.Symlink("vendor", filepath.Join(root, "latest")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("loop", filepath.Join(root, "loop")); err != nil {
+		t.Fatal(err)
+	}
+	
+	fs, err := NewProxy(root, ProxyOptions{HideDotfiles: true, Hide: []string{"*.psd"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readDirNamesT(t, fs, "/"), "drafts,index.html,latest,vendor"; got != want {
+		t.Errorf("got entries %q, want %q", got, want)
+	}
+	if got := readFileT(t, fs, "/latest/lib.js"); got != "lib" {
+		t.Errorf("got %q through symlink inside root", got)
+	}
+	if err := os.Symlink("index.html", filepath.Join(root, "home.html")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"/latest", "/home.html"} {
+		fi, err := stat(fs, name)
+		if err != nil || fi.Name() != name[1:] {
+			t.Errorf("Stat(%q): got %v, %v, want the requested name", name, fi, err)
+		}
+	}
+	for _, name := range []string{"/.git/config", "/drafts/a.psd"} {
+		if _, err := fs.Open(name); !os.IsNotExist(err) {
+			t.Errorf("Open(%q): got %v, want not exist error", name, err)
+		}
+	}
+	for _, name := range []string{"/escape/passwords.txt", "../outside/passwords.txt"} {
+		if _, err := fs.Open(name); !errors.Is(err, ErrOutsideRoot) {
+			t.Errorf("Open(%q): got %v, want ErrOutsideRoot", name, err)
+		}
+	}
+	if _, err := fs.Open("/index.html/child"); !os.IsNotExist(err) {
+		t.Errorf("Open below a file: got %v, want not exist error", err)
+	}
+	// A file replaced by a symbolic link after it was resolved.
+	if err := os.Symlink(filepath.Join(tempDir, "outside", "passwords.txt"), filepath.Join(root, "swapped")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.(*proxyFS).open("/swapped", filepath.Join(root, "swapped")); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("open of a swapped file: got %v, want ErrOutsideRoot", err)
+	}
+	var loopErr *ProxyError
+	if _, err := fs.Open("/loop"); !errors.As(err, &loopErr) || os.IsNotExist(err) {
+		t.Errorf("Open of a symlink loop: got %v, want *ProxyError", err)
+	}
+	
+	_, err = NewProxy(filepath.Join(root, "index.html"), ProxyOptions{})
+	var pe *ProxyError
+	if !errors.As(err, &pe) || !errors.Is(err, ErrNotDir) {
+		t.Errorf("proxy of a file: got %v, want *ProxyError with ErrNotDir", err)
+	}
+	if _, err := NewProxy(filepath.Join(tempDir, "missing"), ProxyOptions{}); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("proxy of missing dir: got %v, want not exist error", err)
+	}
+	if _, err := NewProxy(root, ProxyOptions{Hide: []string{"["}}); err == nil {
+		t.Error("invalid hide pattern: got nil error")
+	}
+}
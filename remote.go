@@ -0,0 +1,327 @@
+package vfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewRemoteHandler returns a handler that serves fs to clients created with Remote. It serves:
+//
+//	GET /meta/{path}   FileMetadata of path as JSON, including directory entries
+//	GET /blob/{path}   the gzip compressed content of the file at path
+//
+// Responses carry an ETag and honor If-None-Match.
+func NewRemoteHandler(fs http.FileSystem) http.Handler {
+	return &remoteHandler{fs: fs}
+}
+
+type remoteHandler struct {
+	fs http.FileSystem
+}
+
+func (h *remoteHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	var (
+		body        []byte
+		contentType string
+		err         error
+	)
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/meta/"):
+		var m *FileMetadata
+		m, err = readMetadata(h.fs, strings.TrimPrefix(req.URL.Path, "/meta"))
+		if err == nil {
+			body, err = json.Marshal(m)
+		}
+		contentType = "application/json"
+	case strings.HasPrefix(req.URL.Path, "/blob/"):
+		body, err = readGzipBytes(h.fs, strings.TrimPrefix(req.URL.Path, "/blob"))
+		contentType = "application/gzip"
+	default:
+		err = os.ErrNotExist
+	}
+	if os.IsNotExist(err) {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	
+	etag := `"` + blobHash(body) + `"`
+	w.Header().Set("ETag", etag)
+	if req.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if req.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(body)
+}
+
+// readGzipBytes returns the gzip compressed content of the file at path,
+// compressing it only if the file doesn't provide its compressed bytes.
+func readGzipBytes(fs http.FileSystem, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: path, Err: errIsDir}
+	}
+	return readGzipped(f)
+}
+
+// RemoteOptions configures a filesystem returned by Remote.
+type RemoteOptions struct {
+	// Client is used to make requests to the remote service.
+	// If left nil, http.DefaultClient is used.
+	Client *http.Client
+	
+	// TTL is how long fetched metadata and content are used before
+	// being revalidated with the remote service.
+	// If left zero, it defaults to one minute.
+	TTL time.Duration
+	
+	// Cache stores the fetched compressed file contents.
+	// If left nil, a new FS is used.
+	Cache *FS
+}
+
+// Remote returns a read-only http.FileSystem that reads files and directory listings
+// from a service serving NewRemoteHandler at baseURL. Fetched compressed contents are
+// kept in a local FS, and revalidated using ETags once their TTL expires.
+func Remote(baseURL string, opt RemoteOptions) http.FileSystem {
+	if opt.Client == nil {
+		opt.Client = http.DefaultClient
+	}
+	if opt.TTL == 0 {
+		opt.TTL = time.Minute
+	}
+	if opt.Cache == nil {
+		opt.Cache = NewFS()
+	}
+	return &remoteFS{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		opt:     opt,
+		meta:    map[string]*remoteEntry{},
+		blobs:   map[string]*remoteEntry{},
+	}
+}
+
+type remoteFS struct {
+	baseURL string
+	opt     RemoteOptions
+	
+	mu    sync.Mutex
+	meta  map[string]*remoteEntry // Keyed by path.
+	blobs map[string]*remoteEntry // Keyed by path, contents are in opt.Cache.
+}
+
+// remoteEntry is a cached response from the remote service.
+// It's replaced rather than modified when revalidated.
+type remoteEntry struct {
+	etag    string
+	fetched time.Time
+	meta    *FileMetadata // Only set for metadata.
+}
+
+func (r *remoteFS) Open(path string) (http.File, error) {
+	path = pathpkg.Clean("/" + path)
+	m, err := r.metadata(path)
+	if err != nil {
+		return nil, err
+	}
+	
+	if m.IsDir {
+		d := &DirInfo{name: m.Name, modTime: m.ModTime, mode: m.Mode.Perm()}
+		for _, e := range m.Entries {
+			d.entries = append(d.entries, metadataFileInfo{e})
+		}
+		return &Dir{DirInfo: d, entries: d.entries}, nil
+	}
+	
+	if err := r.fetchBlob(path, m); err != nil {
+		return nil, err
+	}
+	return r.opt.Cache.Open(path)
+}
+
+// metadata returns the metadata of path, fetching or revalidating it if needed.
+func (r *remoteFS) metadata(path string) (*FileMetadata, error) {
+	r.mu.Lock()
+	cached := r.meta[path]
+	r.mu.Unlock()
+	if cached != nil && time.Since(cached.fetched) < r.opt.TTL {
+		return cached.meta, nil
+	}
+	
+	body, etag, err := r.get("/meta", path, cached)
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		r.mu.Lock()
+		r.meta[path] = &remoteEntry{etag: cached.etag, fetched: time.Now(), meta: cached.meta}
+		r.mu.Unlock()
+		return cached.meta, nil
+	}
+	var m FileMetadata
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("decoding metadata of %s: %v", path, err)
+	}
+	r.mu.Lock()
+	r.meta[path] = &remoteEntry{etag: etag, fetched: time.Now(), meta: &m}
+	r.mu.Unlock()
+	return &m, nil
+}
+
+// fetchBlob makes sure the cache holds the current content of the file at path.
+func (r *remoteFS) fetchBlob(path string, m *FileMetadata) error {
+	r.mu.Lock()
+	cached := r.blobs[path]
+	r.mu.Unlock()
+	if cached != nil {
+		if _, err := r.opt.Cache.Stat(path); err != nil {
+			// Evicted from the cache by someone else.
+			cached = nil
+		} else if time.Since(cached.fetched) < r.opt.TTL {
+			return nil
+		}
+	}
+	
+	body, etag, err := r.get("/blob", path, cached)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		r.mu.Lock()
+		r.blobs[path] = &remoteEntry{etag: cached.etag, fetched: time.Now()}
+		r.mu.Unlock()
+		return nil
+	}
+	// The metadata may predate the content, so the size and hash come from the content.
+	size, hash, err := gunzipSizeHash(body)
+	if err != nil {
+		return fmt.Errorf("reading content of %s: %v", path, err)
+	}
+	meta := m.Meta
+	if meta != nil {
+		c := *meta
+		c.Hash, c.CompressedSize = hash, int64(len(body))
+		meta = &c
+	}
+	err = r.opt.Cache.insert(path, &CompressedFileInfo{
+		name:              m.Name,
+		modTime:           m.ModTime,
+		mode:              m.Mode.Perm(),
+		uncompressedSize:  size,
+		compressedContent: body,
+		meta:              meta,
+	})
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.blobs[path] = &remoteEntry{etag: etag, fetched: time.Now()}
+	r.mu.Unlock()
+	return nil
+}
+
+// gunzipSizeHash returns the size and hex encoded SHA-256 of the content
+// compressed in gz, which must be valid gzip bytes.
+func gunzipSizeHash(gz []byte) (int64, string, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return 0, "", err
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, gr)
+	if err != nil {
+		return 0, "", err
+	}
+	if err := gr.Close(); err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// get fetches path from the given endpoint of the remote service. If cached is not nil,
+// the request is conditional and a nil body is returned when the cached entry is still valid.
+func (r *remoteFS) get(endpoint, path string, cached *remoteEntry) (body []byte, etag string, err error) {
+	u := r.baseURL + (&url.URL{Path: endpoint + path}).EscapedPath()
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if cached != nil {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	resp, err := r.opt.Client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err = ioutil.ReadAll(resp.Body)
+		return body, resp.Header.Get("ETag"), err
+	case http.StatusNotModified:
+		if cached == nil {
+			return nil, "", fmt.Errorf("GET %s: unexpected %s", u, resp.Status)
+		}
+		return nil, cached.etag, nil
+	case http.StatusNotFound:
+		return nil, "", &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	default:
+		return nil, "", fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+}
+
+// metadataFileInfo is an os.FileInfo described by FileMetadata.
+type metadataFileInfo struct {
+	m FileMetadata
+}
+
+func (fi metadataFileInfo) Name() string       { return fi.m.Name }
+func (fi metadataFileInfo) Size() int64        { return fi.m.Size }
+func (fi metadataFileInfo) Mode() os.FileMode  { return fi.m.Mode }
+func (fi metadataFileInfo) ModTime() time.Time { return fi.m.ModTime }
+func (fi metadataFileInfo) IsDir() bool        { return fi.m.IsDir }
+func (fi metadataFileInfo) Sys() interface{} {
+	if fi.m.Meta == nil {
+		return nil
+	}
+	return fi.m.Meta
+}
@@ -0,0 +1,87 @@
+package vfs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRemote(t *testing.T) {
+	served := NewFS()
+	served.Add("/docs", "a.txt", []byte(strings.Repeat("a", 100)))
+	served.Add("/docs", "b.txt", []byte("b"))
+	
+	var requests, notModified int32
+	h := NewRemoteHandler(served)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if req.URL.Path == "/meta/docs/race.txt" {
+			// The content changes between the metadata and the content requests.
+			served.Add("/docs", "race.txt", []byte("longer content"))
+		}
+		if rec.Code == http.StatusNotModified {
+			atomic.AddInt32(&notModified, 1)
+		}
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		_, _ = w.Write(rec.Body.Bytes())
+	}))
+	defer ts.Close()
+	
+	cache := NewFS()
+	fs := Remote(ts.URL, RemoteOptions{TTL: time.Hour, Cache: cache})
+	if got := readDirNamesT(t, fs, "/docs"); got != "a.txt,b.txt" {
+		t.Errorf("got entries %q, want %q", got, "a.txt,b.txt")
+	}
+	if got := readFileT(t, fs, "/docs/a.txt"); got != strings.Repeat("a", 100) {
+		t.Errorf("got %q", got)
+	}
+	if _, err := cache.Stat("/docs/a.txt"); err != nil {
+		t.Errorf("content not cached: %v", err)
+	}
+	if _, err := fs.Open("/missing"); err == nil {
+		t.Error("opening missing file: got nil error")
+	}
+	
+	before := atomic.LoadInt32(&requests)
+	_ = readFileT(t, fs, "/docs/a.txt")
+	if got := atomic.LoadInt32(&requests); got != before {
+		t.Errorf("cached read made %d requests", got-before)
+	}
+	
+	// With an expired TTL, unchanged content is revalidated rather than fetched again.
+	fs = Remote(ts.URL, RemoteOptions{TTL: time.Nanosecond, Cache: cache})
+	_ = readFileT(t, fs, "/docs/b.txt")
+	_ = readFileT(t, fs, "/docs/b.txt")
+	if got := atomic.LoadInt32(&notModified); got != 2 {
+		t.Errorf("got %d not modified responses, want 2", got)
+	}
+	served.Add("/docs", "b.txt", []byte("changed"))
+	if got := readFileT(t, fs, "/docs/b.txt"); got != "changed" {
+		t.Errorf("got %q, want %q", got, "changed")
+	}
+	
+	served.Add("/docs", "race.txt", []byte("short"))
+	f, err := fs.Open("/docs/race.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len("longer content")); fi.Size() != want {
+		t.Errorf("got size %d, want %d of the fetched content", fi.Size(), want)
+	}
+	if m, want := Meta(fi), blobHash([]byte("longer content")); m == nil || m.Hash != want {
+		t.Errorf("got meta %+v, want hash %s of the fetched content", m, want)
+	}
+}
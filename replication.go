@@ -0,0 +1,364 @@
+package vfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	pathpkg "path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Manifest describes the contents of a replicated FS at a specific version.
+type Manifest struct {
+	Version uint64          `json:"version"`
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry describes a single file, directory or symbolic link in a Manifest.
+type ManifestEntry struct {
+	Path    string      `json:"path"`
+	IsDir   bool        `json:"isDir,omitempty"`
+	Symlink bool        `json:"symlink,omitempty"`
+	Target  string      `json:"target,omitempty"` // Target of a symbolic link.
+	Size    int64       `json:"size,omitempty"`   // Uncompressed size of a file.
+	Hash    string      `json:"hash,omitempty"`   // Hex encoded SHA-256 of the gzip compressed content of a file.
+	Mode    os.FileMode `json:"mode"`             // Permission bits.
+	ModTime time.Time   `json:"modTime"`
+	Version uint64      `json:"version"`        // Version at which the path last changed.
+	Meta    *FileMeta   `json:"meta,omitempty"` // Metadata of a file.
+}
+
+// NewReplicationHandler returns a handler that lets followers replicate fs. It serves:
+//
+//	GET /manifest      the current Manifest as JSON
+//	GET /blob/{path}   the gzip compressed content of the file at path
+func NewReplicationHandler(fs *FS) http.Handler {
+	return &replicationHandler{fs: fs, hashes: map[*CompressedFileInfo]string{}}
+}
+
+type replicationHandler struct {
+	fs *FS
+	
+	mu     sync.Mutex
+	hashes map[*CompressedFileInfo]string // Cache of content hashes, file definitions are immutable.
+}
+
+func (h *replicationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	switch {
+	case req.URL.Path == "/manifest":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(h.manifest())
+	case strings.HasPrefix(req.URL.Path, "/blob/"):
+		fi, err := h.fs.Stat(strings.TrimPrefix(req.URL.Path, "/blob"))
+		if err != nil || fi.IsDir() {
+			http.Error(w, "404 page not found", http.StatusNotFound)
+			return
+		}
+		f, err := compressed(fi)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("ETag", `"`+h.hash(f)+`"`)
+		_, _ = w.Write(f.compressedContent)
+	default:
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	}
+}
+
+func (h *replicationHandler) manifest() *Manifest {
+	paths, versions, version := h.fs.snapshot()
+	m := &Manifest{Version: version, Entries: make([]ManifestEntry, 0, len(paths))}
+	live := map[*CompressedFileInfo]bool{}
+	for path, v := range paths {
+		fi := v.(os.FileInfo)
+		e := ManifestEntry{Path: path, Mode: fi.Mode().Perm(), ModTime: fi.ModTime(), Version: versions[path]}
+		switch link := v.(type) {
+		case *DirInfo:
+			e.IsDir = true
+		case *symlinkInfo:
+			e.Symlink, e.Target = true, link.target
+		default:
+			f, err := compressed(v)
+			if err != nil {
+				// Files whose content can't be produced are left out, as they can't be opened.
+				continue
+			}
+			e.Size = f.uncompressedSize
+			e.Hash = h.hash(f)
+			e.Meta = f.metadata()
+			live[f] = true
+		}
+		m.Entries = append(m.Entries, e)
+	}
+	sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Path < m.Entries[j].Path })
+	
+	// Forget hashes of files that are gone.
+	h.mu.Lock()
+	for f := range h.hashes {
+		if !live[f] {
+			delete(h.hashes, f)
+		}
+	}
+	h.mu.Unlock()
+	return m
+}
+
+func (h *replicationHandler) hash(f *CompressedFileInfo) string {
+	h.mu.Lock()
+	defer func() {
+		h.mu.Unlock()
+	}()
+	
+	if s, ok := h.hashes[f]; ok {
+		return s
+	}
+	s := blobHash(f.compressedContent)
+	h.hashes[f] = s
+	return s
+}
+
+func blobHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// FollowerOptions configures a Follower.
+type FollowerOptions struct {
+	// Interval between synchronizations done by Run.
+	// If left zero, it defaults to 10 seconds.
+	Interval time.Duration
+	
+	// Client is used to make requests to the primary.
+	// If left nil, http.DefaultClient is used.
+	Client *http.Client
+	
+	// OnError is called by Run when a synchronization fails.
+	OnError func(error)
+}
+
+// Follower keeps an FS in sync with a primary served by NewReplicationHandler.
+// Only files whose content changed are transferred, in their compressed form,
+// and every synchronization is applied to the FS atomically.
+type Follower struct {
+	fs      *FS
+	baseURL string
+	opt     FollowerOptions
+	
+	syncMu sync.Mutex // Serializes synchronizations.
+	
+	mu             sync.Mutex
+	applied        map[string]appliedFile // Files applied by the last synchronization.
+	synced         bool                   // At least one synchronization was applied.
+	version        uint64                 // Primary version last applied.
+	primaryVersion uint64                 // Primary version last seen.
+	lastSync       time.Time
+}
+
+// appliedFile is a file written to fs by a synchronization.
+type appliedFile struct {
+	hash string
+	info *CompressedFileInfo
+}
+
+// NewFollower returns a Follower that replicates the primary at baseURL into fs.
+func NewFollower(fs *FS, baseURL string, opt FollowerOptions) *Follower {
+	if opt.Interval == 0 {
+		opt.Interval = 10 * time.Second
+	}
+	if opt.Client == nil {
+		opt.Client = http.DefaultClient
+	}
+	return &Follower{
+		fs:      fs,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		opt:     opt,
+		applied: map[string]appliedFile{},
+	}
+}
+
+// Run synchronizes periodically until ctx is done.
+func (f *Follower) Run(ctx context.Context) error {
+	t := time.NewTicker(f.opt.Interval)
+	defer t.Stop()
+	for {
+		if err := f.Sync(ctx); err != nil && f.opt.OnError != nil {
+			f.opt.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Sync fetches the primary manifest and applies any changes to fs.
+func (f *Follower) Sync(ctx context.Context) error {
+	f.syncMu.Lock()
+	defer func() {
+		f.syncMu.Unlock()
+	}()
+	
+	var m Manifest
+	b, err := f.get(ctx, "/manifest")
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("decoding manifest: %v", err)
+	}
+	
+	f.mu.Lock()
+	if m.Version > f.primaryVersion {
+		f.primaryVersion = m.Version
+	}
+	upToDate := f.synced && m.Version == f.version
+	applied := f.applied
+	f.mu.Unlock()
+	if upToDate {
+		f.markSynced()
+		return nil
+	}
+	
+	staging := NewFS()
+	newApplied := map[string]appliedFile{}
+	for _, e := range m.Entries {
+		if e.IsDir {
+			// Entries are sorted, so each directory is created before its contents.
+			dir := &DirInfo{name: pathpkg.Base(e.Path), modTime: e.ModTime, mode: e.Mode}
+			if err := staging.insert(e.Path, dir); err != nil {
+				return err
+			}
+			continue
+		}
+		if e.Symlink {
+			link := &symlinkInfo{name: pathpkg.Base(e.Path), modTime: e.ModTime, target: e.Target}
+			if err := staging.insert(e.Path, link); err != nil {
+				return err
+			}
+			continue
+		}
+		if a, ok := applied[e.Path]; ok && a.hash == e.Hash {
+			// Reuse the file if it wasn't changed locally since it was applied.
+			if fi, err := f.fs.Stat(e.Path); err == nil && fi == os.FileInfo(a.info) {
+				if err := staging.insert(e.Path, a.info); err != nil {
+					return err
+				}
+				newApplied[e.Path] = a
+				continue
+			}
+		}
+		blob, err := f.get(ctx, "/blob"+e.Path)
+		if err != nil {
+			return err
+		}
+		if blobHash(blob) != e.Hash {
+			// The file changed since the manifest was taken, try again on the next sync.
+			return fmt.Errorf("blob %s doesn't match manifest hash", e.Path)
+		}
+		cf := &CompressedFileInfo{
+			name:              pathpkg.Base(e.Path),
+			modTime:           e.ModTime,
+			mode:              e.Mode,
+			uncompressedSize:  e.Size,
+			compressedContent: blob,
+			meta:              e.Meta,
+		}
+		if err := staging.insert(e.Path, cf); err != nil {
+			return err
+		}
+		newApplied[e.Path] = appliedFile{hash: e.Hash, info: cf}
+	}
+	
+	if err := f.fs.Replace("/", staging); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.applied = newApplied
+	f.synced = true
+	f.version = m.Version
+	f.mu.Unlock()
+	f.markSynced()
+	return nil
+}
+
+func (f *Follower) markSynced() {
+	f.mu.Lock()
+	f.lastSync = time.Now()
+	f.mu.Unlock()
+}
+
+func (f *Follower) get(ctx context.Context, path string) ([]byte, error) {
+	u := f.baseURL + (&url.URL{Path: path}).EscapedPath()
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.opt.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Version returns the primary version that was last applied.
+func (f *Follower) Version() uint64 {
+	f.mu.Lock()
+	defer func() {
+		f.mu.Unlock()
+	}()
+	
+	return f.version
+}
+
+// PrimaryVersion returns the latest primary version seen.
+func (f *Follower) PrimaryVersion() uint64 {
+	f.mu.Lock()
+	defer func() {
+		f.mu.Unlock()
+	}()
+	
+	return f.primaryVersion
+}
+
+// Lag returns how many versions the follower is behind the latest primary version seen.
+func (f *Follower) Lag() uint64 {
+	f.mu.Lock()
+	defer func() {
+		f.mu.Unlock()
+	}()
+	
+	return f.primaryVersion - f.version
+}
+
+// LastSync returns the time of the last successful synchronization.
+func (f *Follower) LastSync() time.Time {
+	f.mu.Lock()
+	defer func() {
+		f.mu.Unlock()
+	}()
+	
+	return f.lastSync
+}
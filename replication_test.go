@@ -0,0 +1,89 @@
+package vfs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReplication(t *testing.T) {
+	primary := NewFS()
+	primary.Add("/css", "site.css", []byte("body{}"))
+	primary.Add("/", "index.html", []byte("<h1>v1</h1>"))
+	if err := primary.Mkdir("/empty"); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Chmod("/empty", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.Symlink("css/site.css", "/latest.css"); err != nil {
+		t.Fatal(err)
+	}
+	
+	var blobs int32
+	h := NewReplicationHandler(primary)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/blob/") {
+			atomic.AddInt32(&blobs, 1)
+		}
+		h.ServeHTTP(w, req)
+	}))
+	defer ts.Close()
+	
+	replica := NewFS()
+	f := NewFollower(replica, ts.URL, FollowerOptions{})
+	ctx := context.Background()
+	if err := f.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFileT(t, replica, "/css/site.css"); got != "body{}" {
+		t.Errorf("got %q, want %q", got, "body{}")
+	}
+	if fi, err := replica.Stat("/empty"); err != nil || !fi.IsDir() {
+		t.Errorf("empty directory not replicated: %v, %v", fi, err)
+	} else if want, _ := primary.Stat("/empty"); fi.Mode() != want.Mode() || !fi.ModTime().Equal(want.ModTime()) {
+		t.Errorf("directory has mode %v and mod time %v, want %v and %v", fi.Mode(), fi.ModTime(), want.Mode(), want.ModTime())
+	}
+	if target, err := replica.Readlink("/latest.css"); err != nil || target != "css/site.css" {
+		t.Errorf("symbolic link not replicated: got %q, %v", target, err)
+	}
+	if got := readFileT(t, replica, "/latest.css"); got != "body{}" {
+		t.Errorf("got %q through symbolic link, want %q", got, "body{}")
+	}
+	if got := atomic.LoadInt32(&blobs); got != 2 {
+		t.Errorf("initial sync fetched %d blobs, want 2", got)
+	}
+	if f.Version() != primary.Version() || f.Lag() != 0 {
+		t.Errorf("version %d, lag %d, want version %d and no lag", f.Version(), f.Lag(), primary.Version())
+	}
+	
+	primary.Add("/", "index.html", []byte("<h1>v2</h1>"))
+	if err := primary.RemoveAll("/empty"); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&blobs, 0)
+	if err := f.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&blobs); got != 1 {
+		t.Errorf("second sync fetched %d blobs, want only the changed one", got)
+	}
+	if got := readFileT(t, replica, "/index.html"); got != "<h1>v2</h1>" {
+		t.Errorf("got %q, want %q", got, "<h1>v2</h1>")
+	}
+	if _, err := replica.Stat("/empty"); !os.IsNotExist(err) {
+		t.Errorf("removed directory still replicated: %v", err)
+	}
+	
+	atomic.StoreInt32(&blobs, 0)
+	if err := f.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&blobs); got != 0 {
+		t.Errorf("sync without changes fetched %d blobs", got)
+	}
+}
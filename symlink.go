@@ -0,0 +1,139 @@
+package vfs
+
+import (
+	"errors"
+	"os"
+	pathpkg "path"
+	"strings"
+	"time"
+)
+
+// Readlinker is implemented by filesystems that contain symbolic links. Symbolic links
+// are listed by Readdir with the os.ModeSymlink mode bit set, and followed by Open.
+type Readlinker interface {
+	// Readlink returns the target of the symbolic link at name. Targets are
+	// slash separated, and relative to the directory containing the link
+	// unless they start with "/", in which case they are relative to the root.
+	Readlink(name string) (string, error)
+}
+
+// ErrSymlinkLoop is returned when following symbolic links doesn't end,
+// either because links point to each other or because there are too many of them.
+var ErrSymlinkLoop = errors.New("too many levels of symbolic links")
+
+// maxSymlinks is how many symbolic links are followed while resolving a path.
+const maxSymlinks = 40
+
+// Symlink creates newpath as a symbolic link to target. Open and Stat follow symbolic links,
+// other methods operate on the links themselves and don't follow links in parent paths.
+// The parent directory of newpath must already exist.
+func (fs *FS) Symlink(target, newpath string) error {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	newpath = fs.resolveParent(newpath)
+	if _, ok := fs.paths[newpath]; ok || newpath == "/" {
+		return &os.LinkError{Op: "symlink", Old: target, New: newpath, Err: os.ErrExist}
+	}
+	if err := fs.checkParent("symlink", newpath); err != nil {
+		return err
+	}
+	fs.version++
+	return fs.put(newpath, &symlinkInfo{name: pathpkg.Base(newpath), modTime: time.Now(), target: target})
+}
+
+// Readlink returns the target of the symbolic link at path.
+func (fs *FS) Readlink(path string) (string, error) {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	path, err := fs.evalSymlinks(path, false)
+	if err != nil {
+		return "", err
+	}
+	l, ok := fs.paths[path].(*symlinkInfo)
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: os.ErrInvalid}
+	}
+	return l.target, nil
+}
+
+// Lstat is like Stat, but if path is a symbolic link, it describes the link itself.
+func (fs *FS) Lstat(path string) (os.FileInfo, error) {
+	fs.lock.Lock()
+	defer func() {
+		fs.lock.Unlock()
+	}()
+	
+	fs.init()
+	
+	path, err := fs.evalSymlinks(path, false)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := fs.paths[path]
+	if !ok {
+		return nil, &os.PathError{Op: "lstat", Path: path, Err: os.ErrNotExist}
+	}
+	return f.(os.FileInfo), nil
+}
+
+// evalSymlinks resolves path, following symbolic links in it. The last element
+// is only followed if followLast is set. It doesn't check that the result exists.
+func (fs *FS) evalSymlinks(path string, followLast bool) (string, error) {
+	orig := pathpkg.Clean("/" + path)
+	resolved, rest := "/", splitPath(orig)
+	for hops := 0; len(rest) > 0; {
+		p := fs.resolve(pathpkg.Join(resolved, rest[0]))
+		rest = rest[1:]
+		l, ok := fs.paths[p].(*symlinkInfo)
+		if !ok || len(rest) == 0 && !followLast {
+			resolved = p
+			continue
+		}
+		if hops++; hops > maxSymlinks {
+			return "", &os.PathError{Op: "open", Path: orig, Err: ErrSymlinkLoop}
+		}
+		target := l.target
+		if !strings.HasPrefix(target, "/") {
+			target = pathpkg.Join(resolved, target)
+		}
+		resolved, rest = "/", append(splitPath(pathpkg.Clean("/"+target)), rest...)
+	}
+	return resolved, nil
+}
+
+// isSymlink reports whether fi describes a symbolic link.
+func isSymlink(fi os.FileInfo) bool {
+	return fi.Mode()&os.ModeSymlink != 0
+}
+
+// splitPath returns the elements of path, a clean slash separated path starting with "/".
+func splitPath(path string) []string {
+	if path == "/" {
+		return nil
+	}
+	return strings.Split(path[1:], "/")
+}
+
+// symlinkInfo is the definition of a symbolic link.
+type symlinkInfo struct {
+	name    string
+	modTime time.Time
+	target  string
+}
+
+func (l *symlinkInfo) Name() string       { return l.name }
+func (l *symlinkInfo) Size() int64        { return int64(len(l.target)) }
+func (l *symlinkInfo) Mode() os.FileMode  { return 0777 | os.ModeSymlink }
+func (l *symlinkInfo) ModTime() time.Time { return l.modTime }
+func (l *symlinkInfo) IsDir() bool        { return false }
+func (l *symlinkInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,115 @@
+package vfs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSymlink(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/v3", "app.js", []byte("v3"))
+	for link, target := range map[string]string{
+		"/latest":   "v3",
+		"/app.js":   "/latest/app.js",
+		"/dangling": "missing",
+		"/loop1":    "loop2",
+		"/loop2":    "loop1",
+	} {
+		if err := fs.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Symlink("v3", "/latest"); !os.IsExist(err) {
+		t.Errorf("Symlink over existing link: got %v", err)
+	}
+	
+	if got := readFileT(t, fs, "/app.js"); got != "v3" {
+		t.Errorf("got %q", got)
+	}
+	if got := readDirNamesT(t, fs, "/latest"); got != "app.js" {
+		t.Errorf("Readdir through link: got %q", got)
+	}
+	if target, err := fs.Readlink("/app.js"); err != nil || target != "/latest/app.js" {
+		t.Errorf("Readlink: got %q, %v", target, err)
+	}
+	if _, err := fs.Readlink("/v3"); err == nil {
+		t.Error("Readlink of a directory: got nil error")
+	}
+	fi, err := fs.Lstat("/latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Lstat: got mode %v, want symbolic link", fi.Mode())
+	}
+	if fi, err := fs.Stat("/latest"); err != nil || !fi.IsDir() {
+		t.Errorf("Stat: got %v, %v, want directory", fi, err)
+	}
+	if _, err := fs.Open("/dangling"); !os.IsNotExist(err) {
+		t.Errorf("Open dangling link: got %v", err)
+	}
+	if _, err := fs.Open("/loop1"); !errors.Is(err, ErrSymlinkLoop) {
+		t.Errorf("Open link loop: got %v", err)
+	}
+	
+	// Other methods operate on links themselves.
+	if err := fs.RemoveAll("/latest"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFileT(t, fs, "/v3/app.js"); got != "v3" {
+		t.Errorf("got %q after removing link", got)
+	}
+}
+
+func TestWalkSymlinkLoop(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/a/b", "file.txt", nil)
+	if err := fs.Symlink("/a", "/a/b/up"); err != nil {
+		t.Fatal(err)
+	}
+	var loops []string
+	err := Walk(fs, "/", func(path string, fi os.FileInfo, err error) error {
+		if errors.Is(err, ErrSymlinkLoop) {
+			loops = append(loops, path)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loops) != 1 || loops[0] != "/a/b/up" {
+		t.Errorf("got loops at %q, want /a/b/up", loops)
+	}
+}
+
+func TestProxyReadlink(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "v3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"latest":   "v3",
+		"absolute": filepath.Join(dir, "v3"),
+		"outside":  os.TempDir(),
+	} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fs, err := NewProxy(dir, ProxyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := fs.(Readlinker)
+	for link, want := range map[string]string{"/latest": "v3", "/absolute": "/v3"} {
+		if got, err := rl.Readlink(link); err != nil || got != want {
+			t.Errorf("Readlink(%q): got %q, %v, want %q", link, got, err, want)
+		}
+	}
+	if _, err := rl.Readlink("/outside"); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("Readlink outside root: got %v", err)
+	}
+}
@@ -4,27 +4,48 @@
 package main
 
 import (
+	"io/ioutil"
 	"log"
 	"net/http"
-	
+	"os"
+	"time"
+
+	"github.com/gozelle/vfs"
 	"golang.org/x/tools/godoc/vfs/httpfs"
 	"golang.org/x/tools/godoc/vfs/mapfs"
 )
 
 func main() {
-	var fs http.FileSystem = httpfs.New(mapfs.New(map[string]string{
+	// mapfs can't represent empty folders, so a real one is mounted.
+	emptyDir, err := ioutil.TempDir("", "folder-empty")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer os.RemoveAll(emptyDir)
+	if err := os.Chmod(emptyDir, 0755); err != nil {
+		log.Fatalln(err)
+	}
+
+	fs := vfs.NewMountFS()
+	err = fs.Mount("/", httpfs.New(mapfs.New(map[string]string{
 		"sample-file.txt":                "This file compresses well. Blaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaah!",
 		"not-worth-compressing-file.txt": "Its normal contents are here.",
 		"folderA/file1.txt":              "Stuff in /folderA/file1.txt.",
 		"folderA/file2.txt":              "Stuff in /folderA/file2.txt.",
 		"folderB/folderC/file3.txt":      "Stuff in /folderB/folderC/file3.txt.",
-		// TODO: Empty folder somehow?
-		//"folder-empty/":                  "",
-	}))
-	
-	err := vfsgen.Generate(fs, vfsgen.Options{
+	})))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := fs.Mount("/folder-empty", http.Dir(emptyDir)); err != nil {
+		log.Fatalln(err)
+	}
+
+	err = vfs.Generate(fs, vfs.Options{
 		Filename:    "test_vfsdata_test.go",
 		PackageName: "test_test",
+		// Like mapfs files, the empty folder has no mod time, so that the output doesn't change.
+		ModTime: func(string, os.FileInfo) (time.Time, error) { return time.Time{}, nil },
 	})
 	if err != nil {
 		log.Fatalln(err)
@@ -8,11 +8,12 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/gozelle/vfs"
 	"github.com/shurcooL/httpfs/vfsutil"
 	"github.com/shurcooL/httpgzip"
 )
 
-//go:generate go run test_gen.go
+//go:generate go run -tags=generate test_gen.go
 
 // Basic functionality test.
 func Example_basic() {
@@ -41,6 +42,7 @@ func Example_basic() {
 
 	// Output:
 	// /
+	// /folder-empty
 	// /folderA
 	// /folderA/file1.txt
 	// "Stuff in /folderA/file1.txt." <nil>
@@ -97,6 +99,7 @@ func Example_compressed() {
 
 	// Output:
 	// /
+	// /folder-empty
 	// /folderA
 	// /folderA/file1.txt
 	// "Stuff in /folderA/file1.txt." <nil>
@@ -114,7 +117,7 @@ func Example_compressed() {
 	// <not compressed>
 	// /sample-file.txt
 	// "This file compresses well. Blaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaah!" <nil>
-	// "\x1f\x8b\b\x00\x00\x00\x00\x00\x02\xff\n\xc9\xc8,VH\xcb\xccIUH\xce\xcf-(J-.N-V(O\xcd\xc9\xd1Sp\xcaI\x1c\xd4 C\x11\x10\x00\x00\xff\xff\xe7G\x81:\xbd\x00\x00\x00"
+	// "\x1f\x8b\b\x00\x00\x00\x00\x00\x02\xff\n\xc9\xc8,VH\xcb\xccIUH\xce\xcf-(J-.N-V(O\xcd\xc9\xd1Sp\xcaI\x1c\xd4 C\x110\x00\xe7G\x81:\xbd\x00\x00\x00"
 }
 
 func Example_readTwoOpenedCompressedFiles() {
@@ -265,7 +268,7 @@ func Example_seekDir1() {
 	fmt.Println(fisStringer(fis), err)
 
 	// Output:
-	// [ folderA folderB not-worth-compressing-file.txt sample-file.txt ] <nil>
+	// [ folder-empty folderA folderB not-worth-compressing-file.txt sample-file.txt ] <nil>
 }
 
 func Example_seekDir2() {
@@ -295,13 +298,13 @@ func Example_seekDir2() {
 	fmt.Println(fisStringer(fis), err)
 
 	// Output:
-	// [ folderA folderB ] <nil>
-	// [ not-worth-compressing-file.txt ] <nil>
+	// [ folder-empty folderA ] <nil>
+	// [ folderB ] <nil>
 	// <nil>
-	// [ folderA folderB ] <nil>
+	// [ folder-empty folderA ] <nil>
 	// <nil>
-	// [ folderA ] <nil>
-	// [ folderB not-worth-compressing-file.txt sample-file.txt ] <nil>
+	// [ folder-empty ] <nil>
+	// [ folderA folderB not-worth-compressing-file.txt sample-file.txt ] <nil>
 	// [ ] EOF
 }
 
@@ -339,3 +342,33 @@ func Example_pathCleaned() {
 	// file1.txt
 	// "Stuff in /folderA/file1.txt." <nil>
 }
+
+func Example_emptyFolder() {
+	var fs http.FileSystem = assets
+
+	err := vfs.Walk(fs, "/folder-empty", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Println(path, fi.IsDir())
+		return nil
+	})
+	fmt.Println(err)
+
+	f, err := fs.Open("/folder-empty")
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	fis, err := f.Readdir(0)
+	fmt.Println(fisStringer(fis), err)
+	fis, err = f.Readdir(1)
+	fmt.Println(fisStringer(fis), err)
+
+	// Output:
+	// /folder-empty true
+	// <nil>
+	// [ ] <nil>
+	// [ ] EOF
+}
@@ -20,53 +20,79 @@ var assets = func() http.FileSystem {
 		"/": &vfsgen۰DirInfo{
 			name:    "/",
 			modTime: time.Time{},
+			mode:    0755,
+		},
+		"/folder-empty": &vfsgen۰DirInfo{
+			name:    "folder-empty",
+			modTime: time.Time{},
+			mode:    0755,
 		},
 		"/folderA": &vfsgen۰DirInfo{
 			name:    "folderA",
 			modTime: time.Time{},
+			mode:    0755,
 		},
 		"/folderA/file1.txt": &vfsgen۰FileInfo{
 			name:    "file1.txt",
 			modTime: time.Time{},
+			mode:    0444,
 			content: []byte("\x53\x74\x75\x66\x66\x20\x69\x6e\x20\x2f\x66\x6f\x6c\x64\x65\x72\x41\x2f\x66\x69\x6c\x65\x31\x2e\x74\x78\x74\x2e"),
+
+			meta: &vfsgen۰FileMeta{ContentType: "text/plain; charset=utf-8", Hash: "85aa367724db28828d9a4ff2f777688652bdf9d8ee7bda705d9c45456188e05b", Source: "/folderA/file1.txt"},
 		},
 		"/folderA/file2.txt": &vfsgen۰FileInfo{
 			name:    "file2.txt",
 			modTime: time.Time{},
+			mode:    0444,
 			content: []byte("\x53\x74\x75\x66\x66\x20\x69\x6e\x20\x2f\x66\x6f\x6c\x64\x65\x72\x41\x2f\x66\x69\x6c\x65\x32\x2e\x74\x78\x74\x2e"),
+
+			meta: &vfsgen۰FileMeta{ContentType: "text/plain; charset=utf-8", Hash: "02e74e69334e1bc7311a9528c937201520696db7fe82f3ac267b05d51b477e05", Source: "/folderA/file2.txt"},
 		},
 		"/folderB": &vfsgen۰DirInfo{
 			name:    "folderB",
 			modTime: time.Time{},
+			mode:    0755,
 		},
 		"/folderB/folderC": &vfsgen۰DirInfo{
 			name:    "folderC",
 			modTime: time.Time{},
+			mode:    0755,
 		},
 		"/folderB/folderC/file3.txt": &vfsgen۰FileInfo{
 			name:    "file3.txt",
 			modTime: time.Time{},
+			mode:    0444,
 			content: []byte("\x53\x74\x75\x66\x66\x20\x69\x6e\x20\x2f\x66\x6f\x6c\x64\x65\x72\x42\x2f\x66\x6f\x6c\x64\x65\x72\x43\x2f\x66\x69\x6c\x65\x33\x2e\x74\x78\x74\x2e"),
+
+			meta: &vfsgen۰FileMeta{ContentType: "text/plain; charset=utf-8", Hash: "0835a39f81c25aafb80dc27c1b8f44d61a0915167f4c27cc13d52807d2de9e9c", Source: "/folderB/folderC/file3.txt"},
 		},
 		"/not-worth-compressing-file.txt": &vfsgen۰FileInfo{
 			name:    "not-worth-compressing-file.txt",
 			modTime: time.Time{},
+			mode:    0444,
 			content: []byte("\x49\x74\x73\x20\x6e\x6f\x72\x6d\x61\x6c\x20\x63\x6f\x6e\x74\x65\x6e\x74\x73\x20\x61\x72\x65\x20\x68\x65\x72\x65\x2e"),
+
+			meta: &vfsgen۰FileMeta{ContentType: "text/plain; charset=utf-8", Hash: "6d315f2ca0a945e365f52790271b052cfccf3465f3f167159081814b4e4e343f", Source: "/not-worth-compressing-file.txt"},
 		},
 		"/sample-file.txt": &vfsgen۰CompressedFileInfo{
 			name:             "sample-file.txt",
 			modTime:          time.Time{},
+			mode:             0444,
 			uncompressedSize: 189,
 
-			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x0a\xc9\xc8\x2c\x56\x48\xcb\xcc\x49\x55\x48\xce\xcf\x2d\x28\x4a\x2d\x2e\x4e\x2d\x56\x28\x4f\xcd\xc9\xd1\x53\x70\xca\x49\x1c\xd4\x20\x43\x11\x10\x00\x00\xff\xff\xe7\x47\x81\x3a\xbd\x00\x00\x00"),
+			compressedContent: []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x0a\xc9\xc8\x2c\x56\x48\xcb\xcc\x49\x55\x48\xce\xcf\x2d\x28\x4a\x2d\x2e\x4e\x2d\x56\x28\x4f\xcd\xc9\xd1\x53\x70\xca\x49\x1c\xd4\x20\x43\x11\x30\x00\xe7\x47\x81\x3a\xbd\x00\x00\x00"),
+
+			meta: &vfsgen۰FileMeta{ContentType: "text/plain; charset=utf-8", Hash: "e04e6c4c76b1423c36cda88c487e932fd0c9acec7475ef1d08c9b5b26f1d102c", CompressedSize: 55, Encoding: "gzip", Source: "/sample-file.txt"},
 		},
 	}
 	fs["/"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
+		fs["/folder-empty"].(os.FileInfo),
 		fs["/folderA"].(os.FileInfo),
 		fs["/folderB"].(os.FileInfo),
 		fs["/not-worth-compressing-file.txt"].(os.FileInfo),
 		fs["/sample-file.txt"].(os.FileInfo),
 	}
+	fs["/folder-empty"].(*vfsgen۰DirInfo).entries = []os.FileInfo{}
 	fs["/folderA"].(*vfsgen۰DirInfo).entries = []os.FileInfo{
 		fs["/folderA/file1.txt"].(os.FileInfo),
 		fs["/folderA/file2.txt"].(os.FileInfo),
@@ -120,8 +146,10 @@ func (fs vfsgen۰FS) Open(path string) (http.File, error) {
 type vfsgen۰CompressedFileInfo struct {
 	name              string
 	modTime           time.Time
+	mode              os.FileMode
 	compressedContent []byte
 	uncompressedSize  int64
+	meta              *vfsgen۰FileMeta
 }
 
 func (f *vfsgen۰CompressedFileInfo) Readdir(count int) ([]os.FileInfo, error) {
@@ -133,12 +161,16 @@ func (f *vfsgen۰CompressedFileInfo) GzipBytes() []byte {
 	return f.compressedContent
 }
 
+func (f *vfsgen۰CompressedFileInfo) ContentType() string {
+	return f.meta.ContentType
+}
+
 func (f *vfsgen۰CompressedFileInfo) Name() string       { return f.name }
 func (f *vfsgen۰CompressedFileInfo) Size() int64        { return f.uncompressedSize }
-func (f *vfsgen۰CompressedFileInfo) Mode() os.FileMode  { return 0444 }
+func (f *vfsgen۰CompressedFileInfo) Mode() os.FileMode  { return f.mode }
 func (f *vfsgen۰CompressedFileInfo) ModTime() time.Time { return f.modTime }
 func (f *vfsgen۰CompressedFileInfo) IsDir() bool        { return false }
-func (f *vfsgen۰CompressedFileInfo) Sys() interface{}   { return nil }
+func (f *vfsgen۰CompressedFileInfo) Sys() interface{}   { return f.meta }
 
 // vfsgen۰CompressedFile is an opened compressedFile instance.
 type vfsgen۰CompressedFile struct {
@@ -191,7 +223,9 @@ func (f *vfsgen۰CompressedFile) Close() error {
 type vfsgen۰FileInfo struct {
 	name    string
 	modTime time.Time
+	mode    os.FileMode
 	content []byte
+	meta    *vfsgen۰FileMeta
 }
 
 func (f *vfsgen۰FileInfo) Readdir(count int) ([]os.FileInfo, error) {
@@ -201,12 +235,16 @@ func (f *vfsgen۰FileInfo) Stat() (os.FileInfo, error) { return f, nil }
 
 func (f *vfsgen۰FileInfo) NotWorthGzipCompressing() {}
 
+func (f *vfsgen۰FileInfo) ContentType() string {
+	return f.meta.ContentType
+}
+
 func (f *vfsgen۰FileInfo) Name() string       { return f.name }
 func (f *vfsgen۰FileInfo) Size() int64        { return int64(len(f.content)) }
-func (f *vfsgen۰FileInfo) Mode() os.FileMode  { return 0444 }
+func (f *vfsgen۰FileInfo) Mode() os.FileMode  { return f.mode }
 func (f *vfsgen۰FileInfo) ModTime() time.Time { return f.modTime }
 func (f *vfsgen۰FileInfo) IsDir() bool        { return false }
-func (f *vfsgen۰FileInfo) Sys() interface{}   { return nil }
+func (f *vfsgen۰FileInfo) Sys() interface{}   { return f.meta }
 
 // vfsgen۰File is an opened file instance.
 type vfsgen۰File struct {
@@ -218,10 +256,22 @@ func (f *vfsgen۰File) Close() error {
 	return nil
 }
 
+// vfsgen۰FileMeta is the metadata of a file, returned by Sys.
+// It's identical to vfs.FileMeta, use vfs.Meta to get it.
+type vfsgen۰FileMeta = struct {
+	ContentType    string            `json:"contentType,omitempty"`
+	Hash           string            `json:"hash,omitempty"`
+	CompressedSize int64             `json:"compressedSize,omitempty"`
+	Encoding       string            `json:"encoding,omitempty"`
+	Source         string            `json:"source,omitempty"`
+	Values         map[string]string `json:"values,omitempty"`
+}
+
 // vfsgen۰DirInfo is a static definition of a directory.
 type vfsgen۰DirInfo struct {
 	name    string
 	modTime time.Time
+	mode    os.FileMode
 	entries []os.FileInfo
 }
 
@@ -233,7 +283,7 @@ func (d *vfsgen۰DirInfo) Stat() (os.FileInfo, error) { return d, nil }
 
 func (d *vfsgen۰DirInfo) Name() string       { return d.name }
 func (d *vfsgen۰DirInfo) Size() int64        { return 0 }
-func (d *vfsgen۰DirInfo) Mode() os.FileMode  { return 0755 | os.ModeDir }
+func (d *vfsgen۰DirInfo) Mode() os.FileMode  { return d.mode | os.ModeDir }
 func (d *vfsgen۰DirInfo) ModTime() time.Time { return d.modTime }
 func (d *vfsgen۰DirInfo) IsDir() bool        { return true }
 func (d *vfsgen۰DirInfo) Sys() interface{}   { return nil }
@@ -3,7 +3,9 @@ package vfs
 import (
 	fsi "io/fs"
 	"net/http"
+	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 )
 
@@ -24,7 +26,7 @@ func Walk(fs http.FileSystem, root string, fn WalkFunc) (err error) {
 	if err != nil {
 		err = fn(root, nil, err)
 	} else {
-		err = walk(fs, root, info, fn)
+		err = walk(fs, root, info, nil, fn)
 	}
 	
 	if err == SkipDir {
@@ -35,13 +37,21 @@ func Walk(fs http.FileSystem, root string, fn WalkFunc) (err error) {
 	return
 }
 
-// walk recursively descends path, calling walkFn.
-func walk(fs http.FileSystem, path string, info fsi.FileInfo, walkFn WalkFunc) error {
+// walk recursively descends path, calling walkFn. ancestors are the
+// directories containing path, used to detect symbolic link loops.
+func walk(fs http.FileSystem, path string, info fsi.FileInfo, ancestors []fsi.FileInfo, walkFn WalkFunc) error {
 	
 	if !info.IsDir() {
 		return walkFn(path, info, nil)
 	}
 	
+	for _, a := range ancestors {
+		if sameDir(a, info) {
+			return walkFn(path, info, &fsi.PathError{Op: "walk", Path: path, Err: ErrSymlinkLoop})
+		}
+	}
+	ancestors = append(ancestors, info)
+	
 	names, err := readDirNames(fs, path)
 	err1 := walkFn(path, info, err)
 	// If err != nil, walk can't walk into this directory.
@@ -64,7 +74,7 @@ func walk(fs http.FileSystem, path string, info fsi.FileInfo, walkFn WalkFunc) e
 				return err
 			}
 		} else {
-			err = walk(fs, filename, i, walkFn)
+			err = walk(fs, filename, i, ancestors[:len(ancestors):len(ancestors)], walkFn)
 			if err != nil {
 				if !i.IsDir() || err != SkipDir {
 					return err
@@ -88,14 +98,7 @@ func stat(fs http.FileSystem, path string) (fsi.FileInfo, error) {
 }
 
 func readDirNames(fs http.FileSystem, dirname string) ([]string, error) {
-	f, err := fs.Open(dirname)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = f.Close()
-	}()
-	infos, err := f.Readdir(-1)
+	infos, err := readDir(fs, dirname)
 	if err != nil {
 		return nil, err
 	}
@@ -108,3 +111,25 @@ func readDirNames(fs http.FileSystem, dirname string) ([]string, error) {
 	sort.Strings(names)
 	return names, nil
 }
+
+func readDir(fs http.FileSystem, dirname string) ([]fsi.FileInfo, error) {
+	f, err := fs.Open(dirname)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return f.Readdir(-1)
+}
+
+// sameDir reports whether a and b describe the same directory, reached through
+// different paths. It recognizes directories on disk, and directories whose
+// os.FileInfo is a pointer to their definition, like those of FS and generated code.
+func sameDir(a, b fsi.FileInfo) bool {
+	if os.SameFile(a, b) {
+		return true
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return va.Kind() == reflect.Ptr && va.Type() == vb.Type() && va.Pointer() == vb.Pointer()
+}
@@ -0,0 +1,190 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"time"
+	
+	"golang.org/x/net/webdav"
+)
+
+// NewWebDAVHandler returns a WebDAV handler serving fs, with locks held in memory.
+// Prefix is stripped from request URL paths, as in webdav.Handler.
+func NewWebDAVHandler(fs *FS, prefix string) http.Handler {
+	return &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: WebDAV(fs),
+		LockSystem: webdav.NewMemLS(),
+	}
+}
+
+// WebDAV returns a webdav.FileSystem backed by fs.
+// Files opened for writing are buffered in memory and stored in fs when closed.
+func WebDAV(fs *FS) webdav.FileSystem {
+	return &webdavFS{fs: fs}
+}
+
+type webdavFS struct {
+	fs *FS
+}
+
+func (w *webdavFS) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if err := w.fs.Mkdir(name); err != nil {
+		return err
+	}
+	if perm.Perm() == 0 {
+		return nil
+	}
+	return w.fs.Chmod(name, perm)
+}
+
+func (w *webdavFS) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	name = pathpkg.Clean("/" + name)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		f, err := w.fs.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		return readOnlyFile{f}, nil
+	}
+	
+	fi, err := w.fs.Stat(name)
+	switch {
+	case err == nil && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case err == nil && fi.IsDir():
+		return nil, &os.PathError{Op: "open", Path: name, Err: errIsDir}
+	case os.IsNotExist(err) && flag&os.O_CREATE == 0:
+		return nil, err
+	case err != nil && !os.IsNotExist(err):
+		return nil, err
+	case err != nil:
+		// Fail early rather than on Close if the file can't be created.
+		if parent, err := w.fs.Stat(pathpkg.Dir(name)); err != nil || !parent.IsDir() {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+	}
+	
+	f := &writableFile{fs: w.fs, path: name, name: pathpkg.Base(name), modTime: time.Now()}
+	// Files that are created or truncated are written even if nothing is written to them.
+	f.modified = err != nil || flag&os.O_TRUNC != 0
+	if err == nil {
+		f.mode = fi.Mode().Perm()
+	} else {
+		f.mode = perm.Perm()
+	}
+	if err == nil && flag&os.O_TRUNC == 0 {
+		r, err := w.fs.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		f.content, err = ioutil.ReadAll(r)
+		_ = r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if flag&os.O_APPEND != 0 {
+		f.pos = int64(len(f.content))
+	}
+	return f, nil
+}
+
+func (w *webdavFS) RemoveAll(ctx context.Context, name string) error {
+	return w.fs.RemoveAll(name)
+}
+
+func (w *webdavFS) Rename(ctx context.Context, oldName, newName string) error {
+	return w.fs.Rename(oldName, newName)
+}
+
+func (w *webdavFS) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return w.fs.Stat(name)
+}
+
+// readOnlyFile is a file opened without write flags.
+type readOnlyFile struct {
+	http.File
+}
+
+func (f readOnlyFile) Write([]byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// writableFile is a file opened for writing. Its content is kept in memory
+// and written to fs on Close, if it was modified.
+type writableFile struct {
+	fs       *FS
+	path     string
+	name     string
+	modTime  time.Time
+	mode     os.FileMode // Permission bits stored on Close, zero for the default 0444.
+	content  []byte
+	pos      int64
+	modified bool
+}
+
+func (f *writableFile) Read(p []byte) (int, error) {
+	if f.pos >= int64(len(f.content)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.content[f.pos:])
+	f.pos += int64(n)
+	return n, nil
+}
+
+func (f *writableFile) Write(p []byte) (int, error) {
+	end := f.pos + int64(len(p))
+	if end > int64(len(f.content)) {
+		f.content = append(f.content, make([]byte, end-int64(len(f.content)))...)
+	}
+	copy(f.content[f.pos:], p)
+	f.pos = end
+	f.modTime = time.Now()
+	f.modified = true
+	return len(p), nil
+}
+
+func (f *writableFile) Seek(offset int64, whence int) (int64, error) {
+	pos := f.pos
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos += offset
+	case io.SeekEnd:
+		pos = int64(len(f.content)) + offset
+	default:
+		return 0, fmt.Errorf("invalid whence value: %v", whence)
+	}
+	if pos < 0 {
+		return 0, fmt.Errorf("negative position: %v", pos)
+	}
+	f.pos = pos
+	return pos, nil
+}
+
+func (f *writableFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, fmt.Errorf("cannot Readdir from file %s", f.name)
+}
+
+func (f *writableFile) Stat() (os.FileInfo, error) {
+	return &CompressedFileInfo{
+		name:             f.name,
+		modTime:          f.modTime,
+		mode:             f.mode,
+		uncompressedSize: int64(len(f.content)),
+	}, nil
+}
+
+func (f *writableFile) Close() error {
+	if !f.modified {
+		return nil
+	}
+	return f.fs.writeFile(f.path, f.content, f.mode)
+}
@@ -0,0 +1,150 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWebDAVHandler(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/templates", "index.html", []byte("<h1>old</h1>"))
+	ts := httptest.NewServer(NewWebDAVHandler(fs, "/dav"))
+	defer ts.Close()
+	
+	do := func(method, path, body string, header map[string]string, wantStatus int) string {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode != wantStatus {
+			t.Fatalf("%s %s: got status %d, want %d: %s", method, path, resp.StatusCode, wantStatus, b)
+		}
+		return string(b)
+	}
+	
+	if got := do("GET", "/dav/templates/index.html", "", nil, http.StatusOK); got != "<h1>old</h1>" {
+		t.Errorf("GET: got %q", got)
+	}
+	do("PUT", "/dav/templates/index.html", "<h1>new</h1>", nil, http.StatusCreated)
+	if got := readFileT(t, fs, "/templates/index.html"); got != "<h1>new</h1>" {
+		t.Errorf("after PUT: got %q", got)
+	}
+	do("PUT", "/dav/missing/file.txt", "x", nil, http.StatusNotFound)
+	
+	do("MKCOL", "/dav/partials", "", nil, http.StatusCreated)
+	do("PUT", "/dav/partials/nav.html", "<nav/>", nil, http.StatusCreated)
+	do("MOVE", "/dav/partials", "", map[string]string{"Destination": ts.URL + "/dav/templates/partials"}, http.StatusCreated)
+	if got := readFileT(t, fs, "/templates/partials/nav.html"); got != "<nav/>" {
+		t.Errorf("after MOVE: got %q", got)
+	}
+	
+	props := do("PROPFIND", "/dav/templates", "", map[string]string{"Depth": "1"}, http.StatusMultiStatus)
+	for _, want := range []string{"/dav/templates/index.html", "/dav/templates/partials/"} {
+		if !strings.Contains(props, want) {
+			t.Errorf("PROPFIND response doesn't contain %q:\n%s", want, props)
+		}
+	}
+	
+	do("DELETE", "/dav/templates/partials", "", nil, http.StatusNoContent)
+	if _, err := fs.Stat("/templates/partials/nav.html"); err == nil {
+		t.Error("file still exists after DELETE")
+	}
+}
+
+func TestWebDAVUnmodified(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/", "config.json", []byte("{}"))
+	if err := fs.Chmod("/config.json", 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	dav := WebDAV(fs)
+	
+	version := fs.Version()
+	f, err := dav.OpenFile(ctx, "/config.json", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Version() != version {
+		t.Error("closing an unmodified file changed the filesystem")
+	}
+	if fi, err := fs.Stat("/config.json"); err != nil || fi.Mode() != 0600 {
+		t.Errorf("Stat after unmodified close: got %v, %v, want mode 0600", fi, err)
+	}
+	
+	f, err = dav.OpenFile(ctx, "/config.json", os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := f.Stat(); err != nil || fi.Mode() != 0600 {
+		t.Errorf("Stat of an opened existing file: got %v, %v, want mode 0600", fi, err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := fs.Stat("/config.json"); err != nil || fi.Mode() != 0600 {
+		t.Errorf("Stat after writing over a file: got %v, %v, want mode 0600", fi, err)
+	}
+	
+	for _, test := range []struct {
+		perm, want os.FileMode
+	}{
+		{0640, 0640},
+		{0, 0444},
+	} {
+		name := fmt.Sprintf("/new-%o.txt", test.perm)
+		f, err := dav.OpenFile(ctx, name, os.O_RDWR|os.O_CREATE, test.perm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := f.Stat(); err != nil || fi.Mode() != test.want {
+			t.Errorf("Stat of created %s: got %v, %v, want mode %v", name, fi, err, test.want)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if fi, err := fs.Stat(name); err != nil || fi.Mode() != test.want {
+			t.Errorf("Stat after creating %s: got %v, %v, want mode %v", name, fi, err, test.want)
+		}
+	}
+	
+	if err := dav.Mkdir(ctx, "/private", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := fs.Stat("/private"); err != nil || fi.Mode() != 0700|os.ModeDir {
+		t.Errorf("Stat after Mkdir: got %v, %v, want mode %v", fi, err, 0700|os.ModeDir)
+	}
+}
+
+func readFileT(t *testing.T, fs http.FileSystem, path string) string {
+	t.Helper()
+	f, err := fs.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
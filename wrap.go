@@ -0,0 +1,238 @@
+package vfs
+
+import (
+	"io"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strings"
+	
+	"github.com/shurcooL/httpgzip"
+)
+
+// MapFunc maps a slash separated path starting with "/" to another one.
+// It reports false if the path has no counterpart.
+type MapFunc func(path string) (string, bool)
+
+// Sub returns an http.FileSystem serving the subtree of fs at dir, with dir as its root.
+// Paths can't escape dir, ".." elements are resolved against the new root.
+func Sub(fs http.FileSystem, dir string) http.FileSystem {
+	dir = pathpkg.Clean("/" + dir)
+	if dir == "/" {
+		return fs
+	}
+	toInternal := func(path string) (string, bool) {
+		return pathpkg.Join(dir, path), true
+	}
+	toExternal := func(path string) (string, bool) {
+		if path == dir {
+			return "/", true
+		}
+		if !strings.HasPrefix(path, dir+"/") {
+			return "", false
+		}
+		return path[len(dir):], true
+	}
+	return Rewrite(fs, toInternal, toExternal)
+}
+
+// Rewrite returns an http.FileSystem that exposes the files of fs under different paths.
+// toInternal maps the paths being opened to paths in fs, and toExternal maps paths in fs back,
+// it's used to name directory entries. Entries that toExternal rejects, or maps to another
+// directory, are left out of directory listings.
+//
+// Files opened through Sub and Rewrite keep the optional GzipBytes,
+// NotWorthGzipCompressing and ContentType methods of the files they wrap.
+// If fs implements Readlinker, so does the returned filesystem. Absolute link
+// targets are mapped with toExternal, those it rejects are an ErrOutsideRoot error.
+func Rewrite(fs http.FileSystem, toInternal, toExternal MapFunc) http.FileSystem {
+	r := &rewriteFS{fs: fs, toInternal: toInternal, toExternal: toExternal}
+	if rl, ok := fs.(Readlinker); ok {
+		return &readlinkRewriteFS{rewriteFS: r, rl: rl}
+	}
+	return r
+}
+
+type rewriteFS struct {
+	fs         http.FileSystem
+	toInternal MapFunc
+	toExternal MapFunc
+}
+
+// internal returns the clean external path of name, and the path it maps to in r.fs.
+func (r *rewriteFS) internal(op, name string) (path, internal string, err error) {
+	path = pathpkg.Clean("/" + name)
+	internal, ok := r.toInternal(path)
+	if !ok {
+		return path, "", &os.PathError{Op: op, Path: path, Err: os.ErrNotExist}
+	}
+	return path, pathpkg.Clean("/" + internal), nil
+}
+
+// external returns err reporting path rather than the internal path, so as not to reveal it.
+func external(err error, path string) error {
+	if pe, ok := err.(*os.PathError); ok {
+		return &os.PathError{Op: pe.Op, Path: path, Err: pe.Err}
+	}
+	return err
+}
+
+func (r *rewriteFS) Open(name string) (http.File, error) {
+	path, internal, err := r.internal("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.fs.Open(internal)
+	if err != nil {
+		return nil, external(err, path)
+	}
+	return wrapFile(f, pathpkg.Base(path), func(fis []os.FileInfo) []os.FileInfo {
+		entries := []os.FileInfo{}
+		for _, fi := range fis {
+			ext, ok := r.toExternal(pathpkg.Join(internal, fi.Name()))
+			if !ok {
+				continue
+			}
+			ext = pathpkg.Clean("/" + ext)
+			if ext == path || pathpkg.Dir(ext) != path {
+				continue
+			}
+			entries = append(entries, withName(fi, pathpkg.Base(ext)))
+		}
+		return sortedEntries(entries)
+	}), nil
+}
+
+type readlinkRewriteFS struct {
+	*rewriteFS
+	rl Readlinker
+}
+
+func (r *readlinkRewriteFS) Readlink(name string) (string, error) {
+	path, internal, err := r.internal("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	target, err := r.rl.Readlink(internal)
+	if err != nil {
+		return "", external(err, path)
+	}
+	if !strings.HasPrefix(target, "/") {
+		return target, nil
+	}
+	ext, ok := r.toExternal(pathpkg.Clean(target))
+	if !ok {
+		return "", &os.PathError{Op: "readlink", Path: path, Err: ErrOutsideRoot}
+	}
+	return pathpkg.Clean("/" + ext), nil
+}
+
+// wrapFile returns f with its Stat name replaced by name, and its directory
+// entries passed through mapEntries. The optional GzipBytes,
+// NotWorthGzipCompressing and ContentType methods of f are kept.
+func wrapFile(f http.File, name string, mapEntries func([]os.FileInfo) []os.FileInfo) http.File {
+	w := &wrappedFile{File: f, name: name, mapEntries: mapEntries}
+	ct, typed := f.(ContentTyper)
+	switch f := f.(type) {
+	case httpgzip.GzipByter:
+		if typed {
+			return &typedGzipWrappedFile{wrappedFile: w, GzipByter: f, ContentTyper: ct}
+		}
+		return &gzipWrappedFile{wrappedFile: w, GzipByter: f}
+	case httpgzip.NotWorthGzipCompressing:
+		if typed {
+			return &typedNotWorthWrappedFile{wrappedFile: w, NotWorthGzipCompressing: f, ContentTyper: ct}
+		}
+		return &notWorthWrappedFile{wrappedFile: w, NotWorthGzipCompressing: f}
+	}
+	if typed {
+		return &typedWrappedFile{wrappedFile: w, ContentTyper: ct}
+	}
+	return w
+}
+
+type wrappedFile struct {
+	http.File
+	name       string
+	mapEntries func([]os.FileInfo) []os.FileInfo
+	entries    []os.FileInfo // Mapped entries, nil until first Readdir.
+	pos        int
+}
+
+type gzipWrappedFile struct {
+	*wrappedFile
+	httpgzip.GzipByter
+}
+
+type notWorthWrappedFile struct {
+	*wrappedFile
+	httpgzip.NotWorthGzipCompressing
+}
+
+type typedWrappedFile struct {
+	*wrappedFile
+	ContentTyper
+}
+
+type typedGzipWrappedFile struct {
+	*wrappedFile
+	httpgzip.GzipByter
+	ContentTyper
+}
+
+type typedNotWorthWrappedFile struct {
+	*wrappedFile
+	httpgzip.NotWorthGzipCompressing
+	ContentTyper
+}
+
+func (f *wrappedFile) Stat() (os.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return withName(fi, f.name), nil
+}
+
+func (f *wrappedFile) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		f.pos = 0
+	}
+	return f.File.Seek(offset, whence)
+}
+
+func (f *wrappedFile) Readdir(count int) ([]os.FileInfo, error) {
+	if f.entries == nil {
+		fis, err := f.File.Readdir(0)
+		if err != nil {
+			return nil, err
+		}
+		f.entries = f.mapEntries(fis)
+	}
+	return readdirPage(f.entries, &f.pos, count)
+}
+
+// withName returns fi, reporting name as its name.
+func withName(fi os.FileInfo, name string) os.FileInfo {
+	if fi.Name() == name {
+		return fi
+	}
+	if r, ok := fi.(renamedFileInfo); ok {
+		fi = r.FileInfo
+	}
+	return renamedFileInfo{FileInfo: fi, name: name}
+}
+
+// readdirPage returns the next count entries starting at *pos, following the
+// semantics of http.File.Readdir, and advances *pos.
+func readdirPage(entries []os.FileInfo, pos *int, count int) ([]os.FileInfo, error) {
+	if *pos >= len(entries) && count > 0 {
+		return nil, io.EOF
+	}
+	if count <= 0 || count > len(entries)-*pos {
+		count = len(entries) - *pos
+	}
+	e := entries[*pos : *pos+count]
+	*pos += count
+	return e, nil
+}
@@ -0,0 +1,121 @@
+package vfs
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	pathpkg "path"
+	"strings"
+	"testing"
+	
+	"github.com/shurcooL/httpgzip"
+)
+
+func TestSub(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/", "secret.txt", []byte("secret"))
+	fs.Add("/public", "index.html", []byte("index"))
+	fs.Add("/public/css", "site.css", []byte("css"))
+	sub := Sub(fs, "/public")
+	
+	if got := readFileT(t, sub, "/css/site.css"); got != "css" {
+		t.Errorf("got %q, want %q", got, "css")
+	}
+	if got := readDirNamesT(t, sub, "/"); got != "css,index.html" {
+		t.Errorf("Readdir: got %q", got)
+	}
+	for _, name := range []string{"/../secret.txt", "../secret.txt", "/css/../../secret.txt"} {
+		if _, err := sub.Open(name); !os.IsNotExist(err) {
+			t.Errorf("Open(%q): got %v, want not exist error", name, err)
+		}
+	}
+	
+	f, err := sub.Open("/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, ok := f.(httpgzip.GzipByter); !ok {
+		t.Errorf("%T doesn't implement GzipBytes", f)
+	}
+	if ct, ok := f.(ContentTyper); !ok || ct.ContentType() != "text/html; charset=utf-8" {
+		t.Errorf("%T doesn't report the content type of the wrapped file", f)
+	}
+	if fi, _ := f.Stat(); fi.Name() != "index.html" {
+		t.Errorf("Stat name: got %q", fi.Name())
+	}
+	
+	for target, link := range map[string]string{"css/site.css": "/relative", "/public/index.html": "/absolute", "/secret.txt": "/escape"} {
+		if err := fs.Symlink(target, "/public"+link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rl, ok := sub.(Readlinker)
+	if !ok {
+		t.Fatalf("%T doesn't implement Readlinker", sub)
+	}
+	for link, want := range map[string]string{"/relative": "css/site.css", "/absolute": "/index.html"} {
+		if got, err := rl.Readlink(link); err != nil || got != want {
+			t.Errorf("Readlink(%q): got %q, %v, want %q", link, got, err, want)
+		}
+	}
+	if _, err := rl.Readlink("/escape"); !errors.Is(err, ErrOutsideRoot) {
+		t.Errorf("Readlink of a link out of the subtree: got %v, want ErrOutsideRoot", err)
+	}
+	if _, ok := Sub(http.Dir("."), "/public").(Readlinker); ok {
+		t.Error("Sub implements Readlinker for a filesystem without symbolic links")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	fs := NewFS()
+	fs.Add("/", "about.html", []byte("about"))
+	fs.Add("/blog", "first.html", []byte("first"))
+	fs.Add("/", "robots.txt", []byte("robots"))
+	// Serve pages without their .html extension, and hide everything else.
+	rewritten := Rewrite(fs, func(path string) (string, bool) {
+		if path == "/" || path == "/blog" {
+			return path, true
+		}
+		return path + ".html", true
+	}, func(path string) (string, bool) {
+		if path == "/blog" {
+			return path, true
+		}
+		if pathpkg.Ext(path) != ".html" {
+			return "", false
+		}
+		return strings.TrimSuffix(path, ".html"), true
+	})
+	
+	if got := readFileT(t, rewritten, "/blog/first"); got != "first" {
+		t.Errorf("got %q, want %q", got, "first")
+	}
+	for dir, want := range map[string]string{"/": "about,blog", "/blog": "first"} {
+		if got := readDirNamesT(t, rewritten, dir); got != want {
+			t.Errorf("Readdir(%q): got %q, want %q", dir, got, want)
+		}
+	}
+	if _, err := rewritten.Open("/about.html"); !os.IsNotExist(err) {
+		t.Errorf("got %v, want not exist error", err)
+	} else if err.(*os.PathError).Path != "/about.html" {
+		t.Errorf("error reveals internal path: %v", err)
+	}
+	
+	var files []string
+	err := Walk(rewritten, "/", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(files, ","); got != "/about,/blog/first" {
+		t.Errorf("Walk: got %q", got)
+	}
+}